@@ -25,6 +25,24 @@ func NewBlob(content []byte) (*Blob, error) {
 	}, nil
 }
 
+// NewGitBlob creates a blob whose hash covers the "blob <size>" header as
+// well as the content, the way git hash-object computes it.
+func NewGitBlob(content []byte) (*Blob, error) {
+	b, err := NewBlob(content)
+	if err != nil {
+		return nil, err
+	}
+
+	serialized, err := b.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	hash := sha1.Sum(serialized)
+	b.Hash = hex.EncodeToString(hash[:])
+
+	return b, nil
+}
+
 func (b *Blob) Serialize() ([]byte, error) {
 	header := fmt.Sprintf("blob %d\x00", b.Size)
 	return append([]byte(header), b.Content...), nil
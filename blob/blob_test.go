@@ -67,3 +67,16 @@ func TestBlobDeserialize(t *testing.T) {
 		t.Errorf("Deserialized blob hash does not match. Expected %s, got %s", originalBlob.Hash, deserializedBlob.Hash)
 	}
 }
+
+func TestNewGitBlob(t *testing.T) {
+	blob, err := NewGitBlob([]byte("hello world\n"))
+	if err != nil {
+		t.Fatalf("Failed to create new blob: %v", err)
+	}
+
+	// git hash-object of "hello world\n"
+	expectedHash := "3b18e512dba79e4c8300dd08aeb37f8e728b8dad"
+	if blob.Hash != expectedHash {
+		t.Errorf("Incorrect hash. Expected %s, got %s", expectedHash, blob.Hash)
+	}
+}
@@ -4,53 +4,292 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 
-	"github.com/nexxeln/mini-git/blob"
+	"github.com/nexxeln/mini-git/ignore"
+	"github.com/nexxeln/mini-git/index"
 	"github.com/nexxeln/mini-git/objects"
-	"github.com/nexxeln/mini-git/repository"
 )
 
-func Add(startPath, filePath string) error {
-	repoRoot, err := repository.FindRoot(startPath)
+const addUsage = "usage: mini-git add [-f] [-A | -u] [<pathspec>...]"
+
+// Add stages the files matched by the pathspecs in args. A pathspec names a
+// file, a directory (everything below it) or a glob in which * and ? also
+// match slashes. Matched files that were deleted from the work tree are
+// removed from the index. With -u only tracked files are considered, and
+// with -A new files are staged as well; both cover the whole tree when no
+// pathspec is given. Ignored files are skipped unless -f is given or they
+// are already tracked.
+func Add(startPath string, args []string) error {
+	r, err := OpenRepo(startPath)
+	if err != nil {
+		return err
+	}
+	return r.Add(startPath, args)
+}
+
+func (r *Repo) Add(startPath string, args []string) error {
+	repoRoot, db := r.Root, r.DB
+
+	all := false
+	update := false
+	force := false
+	var pathspecs []string
+	for _, arg := range args {
+		switch {
+		case arg == "-A" || arg == "--all":
+			all = true
+		case arg == "-u" || arg == "--update":
+			update = true
+		case arg == "-f" || arg == "--force":
+			force = true
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf(addUsage)
+		default:
+			pathspecs = append(pathspecs, arg)
+		}
+	}
+
+	if all && update {
+		return fmt.Errorf("-A and -u are mutually incompatible")
+	}
+	if !all && !update && len(pathspecs) == 0 {
+		return fmt.Errorf("nothing specified, nothing added")
+	}
+
+	specs, err := parsePathspecs(repoRoot, startPath, pathspecs)
+	if err != nil {
+		return err
+	}
+	if len(specs) == 0 {
+		specs = []*pathspec{{arg: "."}}
+	}
+
+	idx, err := index.Read(repoRoot)
 	if err != nil {
-		return fmt.Errorf("not a mini-git repository (or any of the parent directories): %v", err)
+		return err
+	}
+
+	var matcher *ignore.Matcher
+	if !force {
+		matcher, err = ignore.New(repoRoot)
+		if err != nil {
+			return err
+		}
 	}
 
-	absFilePath, err := filepath.Abs(filePath)
+	var files []string
+	if !update {
+		files, err = workingFiles(repoRoot, matcher)
+		if err != nil {
+			return err
+		}
+	}
+
+	paths := make(map[string]bool)
+	for _, spec := range specs {
+		matched := false
+		for _, path := range files {
+			if spec.matches(path) {
+				paths[path] = true
+				matched = true
+			}
+		}
+		for _, entry := range idx.Entries {
+			if spec.matches(entry.Path) {
+				paths[entry.Path] = true
+				matched = true
+			}
+		}
+		if !matched && spec.pattern != "" {
+			if matcher != nil && spec.glob == nil {
+				if ignored, err := pathIgnored(repoRoot, matcher, spec.pattern); err != nil {
+					return err
+				} else if ignored {
+					return fmt.Errorf("path '%s' is ignored by %s; use -f to add it anyway", spec.arg, ignore.FileName)
+				}
+			}
+			return fmt.Errorf("pathspec '%s' did not match any files", spec.arg)
+		}
+	}
+
+	for _, path := range sortedKeys(paths) {
+		if err := stagePath(repoRoot, db, idx, path); err != nil {
+			return err
+		}
+	}
+
+	return idx.Write(repoRoot)
+}
+
+// stagePath brings the index entry for path in line with the work tree.
+func stagePath(repoRoot string, db *objects.Database, idx *index.Index, path string) error {
+	absPath := filepath.Join(repoRoot, filepath.FromSlash(path))
+
+	// Stat before reading so a write racing with the read leaves stat data
+	// that no longer matches, rather than data that hides the change.
+	info, err := os.Lstat(absPath)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute path: %v", err)
+		if os.IsNotExist(err) {
+			idx.Remove(path)
+			return nil
+		}
+		return fmt.Errorf("failed to read file: %v", err)
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("cannot add %s: not a regular file", path)
+	}
+
+	if entry, found := idx.Entry(path); found && idx.Unchanged(entry, info) {
+		return nil
 	}
 
-	content, err := os.ReadFile(absFilePath)
+	content, err := os.ReadFile(absPath)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %v", err)
 	}
 
-	b, err := blob.NewBlob(content)
+	b, err := db.NewBlob(content)
 	if err != nil {
 		return fmt.Errorf("failed to create blob: %v", err)
 	}
 
-	if err := objects.Store(repoRoot, b); err != nil {
+	if _, err := db.Put(b); err != nil {
 		return fmt.Errorf("failed to store blob: %v", err)
 	}
 
-	indexPath := filepath.Join(repoRoot, ".mini-git", "index")
-	f, err := os.OpenFile(indexPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		return fmt.Errorf("failed to open index file: %v", err)
+	idx.Add(index.NewEntry(path, b.Hash, info))
+	return nil
+}
+
+// pathspec is a command-line path converted to a slash-separated pattern
+// relative to the repository root. An empty pattern matches everything.
+type pathspec struct {
+	arg     string
+	pattern string
+	glob    *regexp.Regexp
+}
+
+func parsePathspecs(repoRoot, startPath string, args []string) ([]*pathspec, error) {
+	var specs []*pathspec
+	for _, arg := range args {
+		absPath := arg
+		if !filepath.IsAbs(absPath) {
+			absPath = filepath.Join(startPath, arg)
+		}
+
+		relPath, err := filepath.Rel(repoRoot, absPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get relative path: %v", err)
+		}
+		relPath = filepath.ToSlash(relPath)
+		if relPath == ".." || strings.HasPrefix(relPath, "../") {
+			return nil, fmt.Errorf("'%s' is outside repository", arg)
+		}
+		if relPath == "." {
+			relPath = ""
+		}
+
+		spec := &pathspec{arg: arg, pattern: relPath}
+		if strings.ContainsAny(relPath, "*?[") {
+			spec.glob, err = globRegexp(relPath)
+			if err != nil {
+				return nil, fmt.Errorf("invalid pathspec '%s': %v", arg, err)
+			}
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+func (s *pathspec) matches(path string) bool {
+	if s.pattern == "" || path == s.pattern || strings.HasPrefix(path, s.pattern+"/") {
+		return true
 	}
-	defer f.Close()
+	return s.glob != nil && s.glob.MatchString(path)
+}
 
-	relPath, err := filepath.Rel(repoRoot, absFilePath)
-	if err != nil {
-		return fmt.Errorf("failed to get relative path: %v", err)
+// globRegexp translates a glob into a regular expression. As in Git
+// pathspecs, * and ? match slashes too, so "*.go" matches files in every
+// directory.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			expr.WriteString(".*")
+		case '?':
+			expr.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated character class")
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
 	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
 
-	_, err = fmt.Fprintf(f, "%s %s\n", b.Hash, relPath)
+// pathIgnored reports whether the existing work tree path is ignored.
+func pathIgnored(repoRoot string, matcher *ignore.Matcher, path string) (bool, error) {
+	info, err := os.Lstat(filepath.Join(repoRoot, filepath.FromSlash(path)))
 	if err != nil {
-		return fmt.Errorf("failed to write to index file: %v", err)
+		return false, nil
 	}
+	return matcher.Ignored(path, info.IsDir())
+}
 
-	return nil
+// workingFiles lists the regular files in the work tree as sorted,
+// slash-separated paths relative to the repository root, leaving out those
+// matcher ignores when it is not nil.
+func workingFiles(repoRoot string, matcher *ignore.Matcher) ([]string, error) {
+	var files []string
+	err := filepath.Walk(repoRoot, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() && info.Name() == ".mini-git" {
+			return filepath.SkipDir
+		}
+
+		relPath, err := filepath.Rel(repoRoot, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+
+		if matcher != nil && relPath != "." {
+			ignored, err := matcher.Ignored(relPath, info.IsDir())
+			if err != nil {
+				return err
+			}
+			if ignored {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+		}
+
+		if info.Mode().IsRegular() {
+			files = append(files, relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list working files: %v", err)
+	}
+	return files, nil
 }
@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// checkStaged fails the test unless exactly the given paths are in the
+// index.
+func checkStaged(t *testing.T, repo string, paths ...string) {
+	t.Helper()
+
+	staged, err := readIndex(repo)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if len(staged) != len(paths) {
+		t.Errorf("Expected %v staged, got %v", paths, staged)
+	}
+	for _, path := range paths {
+		if _, ok := staged[path]; !ok {
+			t.Errorf("Expected %s to be staged, got %v", path, staged)
+		}
+	}
+}
+
+func TestAddPathsDirectoriesAndGlobs(t *testing.T) {
+	repo := tempRepo(t)
+	for _, name := range []string{"a", "b", "src/main.go", "src/lib/util.go", "docs/x.txt", "docs/deep/y.txt", "other"} {
+		writeFile(t, repo, name, name+"\n")
+	}
+
+	if err := Add(repo, []string{"a", "b", "src", "*.txt"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	checkStaged(t, repo, "a", "b", "src/main.go", "src/lib/util.go", "docs/x.txt", "docs/deep/y.txt")
+
+	if err := Add(repo, []string{"missing"}); err == nil {
+		t.Errorf("Expected a pathspec matching nothing to fail")
+	}
+}
+
+func TestAddRelativeToSubdirectory(t *testing.T) {
+	repo := tempRepo(t)
+	writeFile(t, repo, "top", "top\n")
+	writeFile(t, repo, "sub/file", "file\n")
+
+	if err := Add(filepath.Join(repo, "sub"), []string{"file"}); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+	checkStaged(t, repo, "sub/file")
+
+	if err := Add(filepath.Join(repo, "sub"), []string{".."}); err != nil {
+		t.Fatalf("Add of the parent directory failed: %v", err)
+	}
+	checkStaged(t, repo, "sub/file", "top")
+}
+
+func TestAddRefusesIgnoredPaths(t *testing.T) {
+	repo := tempRepo(t)
+	writeFile(t, repo, ".mini-gitignore", "*.log\n")
+	writeFile(t, repo, "debug.log", "noise\n")
+
+	if err := Add(repo, []string{"debug.log"}); err == nil {
+		t.Errorf("Expected adding an ignored file to fail")
+	}
+	if err := Add(repo, []string{"-f", "debug.log"}); err != nil {
+		t.Errorf("Expected -f to add an ignored file: %v", err)
+	}
+}
+
+func TestAddAllAndUpdate(t *testing.T) {
+	repo := tempRepo(t)
+	commitFiles(t, repo, "base", map[string]string{"keep": "keep\n", "gone": "gone\n", "changed": "one\n"})
+
+	writeFile(t, repo, "changed", "two\n")
+	writeFile(t, repo, "new", "new\n")
+	if err := os.Remove(filepath.Join(repo, "gone")); err != nil {
+		t.Fatalf("Failed to remove gone: %v", err)
+	}
+	before := stagedHash(t, repo, "changed")
+
+	if err := Add(repo, []string{"-u"}); err != nil {
+		t.Fatalf("Add -u failed: %v", err)
+	}
+	checkStaged(t, repo, "keep", "changed")
+	if stagedHash(t, repo, "changed") == before {
+		t.Errorf("Expected -u to stage the modification")
+	}
+
+	if err := Add(repo, []string{"-A"}); err != nil {
+		t.Fatalf("Add -A failed: %v", err)
+	}
+	checkStaged(t, repo, "keep", "changed", "new")
+
+	if err := Add(repo, []string{"-A", "-u"}); err == nil {
+		t.Errorf("Expected -A and -u together to fail")
+	}
+}
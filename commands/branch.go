@@ -6,31 +6,42 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/nexxeln/mini-git/repository"
+	"github.com/nexxeln/mini-git/identity"
+	"github.com/nexxeln/mini-git/objects"
+	"github.com/nexxeln/mini-git/refs"
+	"github.com/nexxeln/mini-git/revision"
 )
 
-func Branch(startPath string, args []string) error {
-	repoRoot, err := repository.FindRoot(startPath)
+func Branch(startPath string, args []string, committer identity.Identity) error {
+	r, err := OpenRepo(startPath)
 	if err != nil {
-		return fmt.Errorf("not a mini-git repository (or any of the parent directories): %v", err)
+		return err
 	}
+	return r.Branch(args, committer)
+}
+
+func (r *Repo) Branch(args []string, committer identity.Identity) error {
+	repoRoot, db := r.Root, r.DB
 
 	if len(args) == 0 {
 		// List branches
 		return listBranches(repoRoot)
-	} else if len(args) == 1 {
-		// Create new branch
-		return createBranch(repoRoot, args[0])
+	} else if len(args) <= 2 {
+		// Create new branch, at HEAD unless a start point is given
+		startPoint := ""
+		if len(args) == 2 {
+			startPoint = args[1]
+		}
+		return createBranch(repoRoot, db, args[0], startPoint, committer)
 	}
 
-	return fmt.Errorf("invalid number of arguments for branch command")
+	return fmt.Errorf("usage: mini-git branch [<name> [<start-point>]]")
 }
 
 func listBranches(repoRoot string) error {
-	branchesDir := filepath.Join(repoRoot, ".mini-git", "refs", "heads")
-	entries, err := os.ReadDir(branchesDir)
+	branches, err := refs.List(repoRoot, "refs/heads/")
 	if err != nil {
-		return fmt.Errorf("failed to read branches directory: %v", err)
+		return err
 	}
 
 	currentBranch, err := getCurrentBranch(repoRoot)
@@ -38,11 +49,8 @@ func listBranches(repoRoot string) error {
 		return fmt.Errorf("failed to get current branch: %v", err)
 	}
 
-	for _, entry := range entries {
-		if entry.IsDir() {
-			continue
-		}
-		branchName := entry.Name()
+	for _, ref := range branches {
+		branchName := strings.TrimPrefix(ref, "refs/heads/")
 		if branchName == currentBranch {
 			fmt.Printf("* %s\n", branchName)
 		} else {
@@ -53,17 +61,42 @@ func listBranches(repoRoot string) error {
 	return nil
 }
 
-func createBranch(repoRoot, branchName string) error {
-	headCommitHash, err := getHEADCommitHash(repoRoot)
+func createBranch(repoRoot string, db *objects.Database, branchName, startPoint string, committer identity.Identity) error {
+	if !refs.ValidName(branchName) {
+		return fmt.Errorf("'%s' is not a valid branch name", branchName)
+	}
+
+	ref := "refs/heads/" + branchName
+	if refs.Exists(repoRoot, ref) {
+		return fmt.Errorf("a branch named '%s' already exists", branchName)
+	}
+
+	startHash, err := getHEADCommitHash(repoRoot)
 	if err != nil {
 		return fmt.Errorf("failed to get HEAD commit hash: %v", err)
 	}
 
-	// If there are no commits yet, headCommitHash will be empty
+	if startPoint != "" {
+		startHash, err = revision.ResolveCommit(repoRoot, db, startPoint)
+		if err != nil {
+			return err
+		}
+	}
+
+	// If there are no commits yet, startHash will be empty
 	// In this case, we'll create an empty branch
-	branchPath := filepath.Join(repoRoot, ".mini-git", "refs", "heads", branchName)
-	if err := os.WriteFile(branchPath, []byte(headCommitHash), 0644); err != nil {
-		return fmt.Errorf("failed to create branch: %v", err)
+	if startHash == "" {
+		branchPath := filepath.Join(repoRoot, ".mini-git", "refs", "heads", branchName)
+		if err := os.WriteFile(branchPath, []byte(""), 0644); err != nil {
+			return fmt.Errorf("failed to create branch: %v", err)
+		}
+	} else {
+		if startPoint == "" {
+			startPoint = "HEAD"
+		}
+		if err := refs.Update(repoRoot, ref, startHash, committer, "branch: Created from "+startPoint); err != nil {
+			return fmt.Errorf("failed to create branch: %v", err)
+		}
 	}
 
 	fmt.Printf("Created branch '%s'\n", branchName)
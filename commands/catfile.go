@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/nexxeln/mini-git/objects"
+	"github.com/nexxeln/mini-git/revision"
+	"github.com/nexxeln/mini-git/tree"
+)
+
+func CatFile(startPath string, args []string) error {
+	r, err := OpenRepo(startPath)
+	if err != nil {
+		return err
+	}
+	return r.CatFile(args)
+}
+
+func (r *Repo) CatFile(args []string) error {
+	repoRoot, db := r.Root, r.DB
+
+	if len(args) != 2 || (args[0] != "-t" && args[0] != "-s" && args[0] != "-p") {
+		return fmt.Errorf("usage: mini-git cat-file (-t | -s | -p) <object>")
+	}
+
+	hash, err := revision.Resolve(repoRoot, db, args[1])
+	if err != nil {
+		return fmt.Errorf("not a valid object name %s: %v", args[1], err)
+	}
+
+	kind, content, err := db.ReadRaw(hash)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "-t":
+		fmt.Println(kind)
+	case "-s":
+		fmt.Println(len(content))
+	case "-p":
+		if kind != objects.TypeTree {
+			os.Stdout.Write(content)
+			return nil
+		}
+
+		// Trees may be binary, so they are listed entry by entry.
+		obj, _, err := db.Retrieve(hash)
+		if err != nil {
+			return err
+		}
+		for _, entry := range obj.(*tree.Tree).Entries {
+			mode, entryType := "100644", objects.TypeBlob
+			if entry.Type == tree.EntryTypeTree {
+				mode, entryType = "040000", objects.TypeTree
+			}
+			fmt.Printf("%s %s %s\t%s\n", mode, entryType, entry.Hash, entry.Name)
+		}
+	}
+
+	return nil
+}
@@ -1,111 +1,435 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"syscall"
 
+	"github.com/nexxeln/mini-git/history"
+	"github.com/nexxeln/mini-git/identity"
+	"github.com/nexxeln/mini-git/ignore"
+	"github.com/nexxeln/mini-git/index"
 	"github.com/nexxeln/mini-git/objects"
-	"github.com/nexxeln/mini-git/repository"
-	"github.com/nexxeln/mini-git/tree"
+	"github.com/nexxeln/mini-git/refs"
+	"github.com/nexxeln/mini-git/revision"
 )
 
-func Checkout(startPath string, args []string) error {
-	if len(args) != 1 {
-		return fmt.Errorf("usage: mini-git checkout <branch-name>")
+const checkoutUsage = "usage: mini-git checkout [-f] <branch> | checkout [-f] <commit> | checkout [-f] -b <new-branch> [<start-point>]"
+
+// Checkout switches to a branch, or detaches HEAD at any other commit,
+// updating the work tree to match. With -b it first creates the branch at
+// the start point, HEAD by default.
+func Checkout(startPath string, args []string, committer identity.Identity) error {
+	r, err := OpenRepo(startPath)
+	if err != nil {
+		return err
+	}
+	return r.Checkout(args, committer)
+}
+
+func (r *Repo) Checkout(args []string, committer identity.Identity) error {
+	repoRoot, db := r.Root, r.DB
+
+	force := false
+	newBranch := ""
+	var names []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-f" || arg == "--force":
+			force = true
+		case arg == "-b":
+			if i+1 == len(args) || newBranch != "" {
+				return fmt.Errorf(checkoutUsage)
+			}
+			i++
+			newBranch = args[i]
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf(checkoutUsage)
+		default:
+			names = append(names, arg)
+		}
+	}
+
+	if newBranch != "" {
+		if len(names) > 1 {
+			return fmt.Errorf(checkoutUsage)
+		}
+		startPoint := ""
+		if len(names) == 1 {
+			startPoint = names[0]
+		}
+		return checkoutNewBranch(repoRoot, db, newBranch, startPoint, committer, force)
+	}
+
+	if len(names) != 1 {
+		return fmt.Errorf(checkoutUsage)
+	}
+
+	name := names[0]
+	if refs.Exists(repoRoot, "refs/heads/"+name) {
+		return checkoutBranch(repoRoot, db, name, committer, force)
+	}
+
+	hash, err := revision.ResolveCommit(repoRoot, db, name)
+	if err != nil {
+		return fmt.Errorf("'%s' is neither a branch nor a commit: %v", name, err)
 	}
+	return checkoutDetached(repoRoot, db, name, hash, committer, force)
+}
 
-	repoRoot, err := repository.FindRoot(startPath)
+func checkoutBranch(repoRoot string, db *objects.Database, branchName string, committer identity.Identity, force bool) error {
+	ref := "refs/heads/" + branchName
+	newHash, err := refs.Read(repoRoot, ref)
 	if err != nil {
-		return fmt.Errorf("not a mini-git repository (or any of the parent directories): %v", err)
+		return err
+	}
+
+	if err := moveHead(repoRoot, db, ref, newHash, branchName, "", committer, force); err != nil {
+		return err
 	}
 
-	branchName := args[0]
-	return checkoutBranch(repoRoot, branchName)
+	fmt.Printf("Switched to branch '%s'\n", branchName)
+	return nil
 }
 
-func checkoutBranch(repoRoot, branchName string) error {
-	branchPath := filepath.Join(repoRoot, ".mini-git", "refs", "heads", branchName)
-	if _, err := os.Stat(branchPath); os.IsNotExist(err) {
-		return fmt.Errorf("branch '%s' does not exist", branchName)
+func checkoutNewBranch(repoRoot string, db *objects.Database, branchName, startPoint string, committer identity.Identity, force bool) error {
+	if !refs.ValidName(branchName) {
+		return fmt.Errorf("'%s' is not a valid branch name", branchName)
+	}
+
+	ref := "refs/heads/" + branchName
+	if refs.Exists(repoRoot, ref) {
+		return fmt.Errorf("a branch named '%s' already exists", branchName)
 	}
 
-	commitHash, err := os.ReadFile(branchPath)
+	// Without a start point the new branch starts at HEAD, which may not
+	// have a commit yet.
+	_, startHash, err := refs.Head(repoRoot)
 	if err != nil {
-		return fmt.Errorf("failed to read branch file: %v", err)
+		return err
+	}
+	if startPoint != "" {
+		startHash, err = revision.ResolveCommit(repoRoot, db, startPoint)
+		if err != nil {
+			return err
+		}
+	} else {
+		startPoint = "HEAD"
 	}
 
+	if err := moveHead(repoRoot, db, ref, startHash, branchName, startPoint, committer, force); err != nil {
+		return err
+	}
+
+	fmt.Printf("Switched to a new branch '%s'\n", branchName)
+	return nil
+}
+
+func checkoutDetached(repoRoot string, db *objects.Database, name, hash string, committer identity.Identity, force bool) error {
+	if err := moveHead(repoRoot, db, "", hash, name, "", committer, force); err != nil {
+		return err
+	}
+
+	c, err := db.Commit(hash)
+	if err != nil {
+		return err
+	}
+	subject, _, _ := strings.Cut(c.Message, "\n")
+
+	fmt.Printf("Note: switching to '%s'.\n\n", name)
+	fmt.Println("You are in 'detached HEAD' state. Commits made here belong to no branch;")
+	fmt.Println("to keep them, create a branch with 'mini-git checkout -b <new-branch-name>'.")
+	fmt.Printf("\nHEAD is now at %s %s\n", shortHash(hash), subject)
+	return nil
+}
+
+// moveHead switches the work tree to the commit at newHash and then points
+// HEAD at ref, or detaches it at newHash when ref is empty. When createdFrom
+// is set, ref is a new branch created at newHash. The work tree is updated
+// first so that a refused checkout leaves HEAD and the refs where they were.
+func moveHead(repoRoot string, db *objects.Database, ref, newHash, target, createdFrom string, committer identity.Identity, force bool) error {
+	headRef, oldHash, err := refs.Head(repoRoot)
+	if err != nil {
+		return err
+	}
+	from := strings.TrimPrefix(headRef, "refs/heads/")
+	if headRef == "" {
+		from = oldHash
+	}
+
+	if err := switchCommit(repoRoot, db, oldHash, newHash, force); err != nil {
+		return err
+	}
+
+	if headRef == "" && oldHash != "" && oldHash != newHash {
+		if err := warnLostCommits(repoRoot, db, oldHash, newHash); err != nil {
+			return err
+		}
+	}
+
+	if createdFrom != "" && newHash != "" {
+		if err := refs.Update(repoRoot, ref, newHash, committer, "branch: Created from "+createdFrom); err != nil {
+			return fmt.Errorf("failed to create branch: %v", err)
+		}
+	}
+
+	headContent := newHash
+	if ref != "" {
+		headContent = "ref: " + ref
+	}
 	headPath := filepath.Join(repoRoot, ".mini-git", "HEAD")
-	headContent := fmt.Sprintf("ref: refs/heads/%s", branchName)
 	if err := os.WriteFile(headPath, []byte(headContent), 0644); err != nil {
 		return fmt.Errorf("failed to update HEAD: %v", err)
 	}
 
-	if len(strings.TrimSpace(string(commitHash))) == 0 {
-		fmt.Printf("Switched to branch '%s'\n", branchName)
-		return nil
-	}
+	message := fmt.Sprintf("checkout: moving from %s to %s", from, target)
+	return refs.AppendLog(repoRoot, "HEAD", oldHash, newHash, committer, message)
+}
 
-	commit, err := objects.RetrieveCommit(repoRoot, strings.TrimSpace(string(commitHash)))
+// warnLostCommits tells the user about commits that only the detached HEAD
+// at oldHash could reach and that are about to be left behind.
+func warnLostCommits(repoRoot string, db *objects.Database, oldHash, newHash string) error {
+	allRefs, err := refs.List(repoRoot, "refs/")
 	if err != nil {
-		return fmt.Errorf("failed to retrieve commit: %v", err)
+		return err
 	}
 
-	tree, err := objects.RetrieveTree(repoRoot, commit.TreeHash)
-	if err != nil {
-		return fmt.Errorf("failed to retrieve tree: %v", err)
+	keep := []string{newHash}
+	for _, ref := range allRefs {
+		// Tags may point at objects other than commits; those keep nothing.
+		if hash, err := revision.ResolveCommit(repoRoot, db, ref); err == nil {
+			keep = append(keep, hash)
+		}
 	}
 
-	if err := updateWorkingDirectory(repoRoot, tree); err != nil {
-		return fmt.Errorf("failed to update working directory: %v", err)
+	lost, err := history.Unreachable(db, oldHash, keep)
+	if err != nil || len(lost) == 0 {
+		return err
 	}
 
-	fmt.Printf("Switched to branch '%s'\n", branchName)
+	noun := "commit"
+	if len(lost) > 1 {
+		noun = "commits"
+	}
+	fmt.Printf("Warning: you are leaving %d %s behind, not connected to any of your branches:\n\n", len(lost), noun)
+	const shown = 5
+	for i, hash := range lost {
+		if i == shown {
+			fmt.Printf("  ... and %d more.\n", len(lost)-shown)
+			break
+		}
+		c, err := db.Commit(hash)
+		if err != nil {
+			return err
+		}
+		subject, _, _ := strings.Cut(c.Message, "\n")
+		fmt.Printf("  %s %s\n", shortHash(hash), subject)
+	}
+	fmt.Printf("\nIf you want to keep them, create a branch now with:\n\n  mini-git branch <new-branch-name> %s\n\n", shortHash(oldHash))
 	return nil
 }
 
-func updateWorkingDirectory(repoRoot string, tree *tree.Tree) error {
-	err := filepath.Walk(repoRoot, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
+// switchCommit updates the work tree from the tree of the commit at oldHash
+// to that of newHash. Either hash may be empty for an unborn branch.
+func switchCommit(repoRoot string, db *objects.Database, oldHash, newHash string, force bool) error {
+	oldFiles, err := readCommitTree(db, oldHash)
+	if err != nil {
+		return err
+	}
+	newFiles, err := readCommitTree(db, newHash)
+	if err != nil {
+		return err
+	}
+	return switchWorkingTree(repoRoot, db, oldFiles, newFiles, force)
+}
+
+// switchWorkingTree updates the work tree and the index from the oldFiles
+// tree to the newFiles tree, touching only the paths that differ between
+// them. Local changes to other files, staged or not, are carried over and
+// untracked files are left alone. Unless force is set, it refuses to start
+// when that would discard local modifications or overwrite untracked files;
+// with force, tracked files and the index are reset to newFiles.
+func switchWorkingTree(repoRoot string, db *objects.Database, oldFiles, newFiles map[string]string, force bool) error {
+	idx, err := index.Read(repoRoot)
+	if err != nil {
+		return err
+	}
+
+	if force {
+		idx = &index.Index{}
+	} else if err := checkOverwrites(repoRoot, db, idx, oldFiles, newFiles, "checkout"); err != nil {
+		return err
+	}
+
+	for _, path := range sortedKeys(oldFiles) {
+		if _, kept := newFiles[path]; kept {
+			continue
+		}
+		if err := removeWorkingFile(repoRoot, path); err != nil {
 			return err
 		}
-		if info.IsDir() {
-			if info.Name() == ".mini-git" {
-				return filepath.SkipDir
-			}
-			return nil
+		idx.Remove(path)
+	}
+
+	for _, path := range sortedKeys(newFiles) {
+		hash := newFiles[path]
+		if oldHash, tracked := oldFiles[path]; tracked && oldHash == hash && !force {
+			continue
 		}
-		relPath, err := filepath.Rel(repoRoot, path)
+		b, err := db.Blob(hash)
 		if err != nil {
+			return fmt.Errorf("failed to retrieve blob for %s: %v", path, err)
+		}
+		if err := writeWorkingFile(repoRoot, path, b.Content); err != nil {
 			return err
 		}
-		if relPath != "." {
-			if err := os.Remove(path); err != nil {
-				return err
-			}
+
+		info, err := os.Stat(filepath.Join(repoRoot, filepath.FromSlash(path)))
+		if err != nil {
+			return err
 		}
-		return nil
-	})
+		idx.Add(index.NewEntry(path, hash, info))
+	}
+
+	return idx.Write(repoRoot)
+}
+
+// checkOverwrites fails, listing the offending files, if switching from
+// oldFiles to newFiles would lose a local modification, staged or not, or
+// overwrite an untracked file. Ignored files count as expendable, as do
+// files that already hold the content they would receive. operation names
+// the command in the error.
+func checkOverwrites(repoRoot string, db *objects.Database, idx *index.Index, oldFiles, newFiles map[string]string, operation string) error {
+	matcher, err := ignore.New(repoRoot)
 	if err != nil {
 		return err
 	}
 
-	for _, entry := range tree.Entries {
-		blob, err := objects.RetrieveBlob(repoRoot, entry.Hash)
-		if err != nil {
-			return fmt.Errorf("failed to retrieve blob for %s: %v", entry.Name, err)
+	var modified, untracked []string
+	blocked := make(map[string]bool)
+	for _, path := range sortedKeys(unionKeys(oldFiles, newFiles)) {
+		oldHash, tracked := oldFiles[path]
+		newHash := newFiles[path]
+		if tracked && oldHash == newHash {
+			continue
 		}
 
-		filePath := filepath.Join(repoRoot, entry.Name)
-		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
-			return fmt.Errorf("failed to create directories for %s: %v", entry.Name, err)
+		if entry, staged := idx.Entry(path); staged && entry.Hash != newHash && (!tracked || entry.Hash != oldHash) {
+			modified = append(modified, path)
+			continue
 		}
 
-		if err := os.WriteFile(filePath, blob.Content, 0644); err != nil {
-			return fmt.Errorf("failed to write file %s: %v", entry.Name, err)
+		hash, exists, err := workingFileHash(repoRoot, db, idx, path)
+		if errors.Is(err, syscall.ENOTDIR) {
+			// A file stands where the new tree needs a directory. A tracked
+			// one is replaced like any other, an untracked one is in the way.
+			blocker := fileInTheWay(repoRoot, path)
+			if _, blockerTracked := oldFiles[blocker]; blocker == "" || blockerTracked || blocked[blocker] {
+				continue
+			}
+			blocked[blocker] = true
+			ignored, err := matcher.Ignored(blocker, false)
+			if err != nil {
+				return err
+			}
+			if !ignored {
+				untracked = append(untracked, blocker)
+			}
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if !exists || hash == newHash || (tracked && hash == oldHash) {
+			continue
+		}
+
+		if tracked {
+			modified = append(modified, path)
+			continue
+		}
+		ignored, err := matcher.Ignored(path, false)
+		if err != nil {
+			return err
+		}
+		if !ignored {
+			untracked = append(untracked, path)
 		}
 	}
 
+	sort.Strings(untracked)
+
+	var problems []string
+	if len(modified) > 0 {
+		problems = append(problems, "your local changes to the following files would be overwritten by "+operation+":\n\t"+strings.Join(modified, "\n\t"))
+	}
+	if len(untracked) > 0 {
+		problems = append(problems, "the following untracked working tree files would be overwritten by "+operation+":\n\t"+strings.Join(untracked, "\n\t"))
+	}
+	if len(problems) > 0 {
+		hint := "commit or remove them"
+		if operation == "checkout" {
+			hint += ", or use --force to discard them"
+		}
+		return fmt.Errorf("%s\n%s", strings.Join(problems, "\n"), hint)
+	}
 	return nil
 }
+
+// workingFileHash returns the blob hash of a work tree file, relying on the
+// index's stat data when it shows the file is unchanged.
+func workingFileHash(repoRoot string, db *objects.Database, idx *index.Index, path string) (string, bool, error) {
+	filePath := filepath.Join(repoRoot, filepath.FromSlash(path))
+	info, err := os.Lstat(filePath)
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if info.IsDir() {
+		// A directory in the way of a file counts as untracked content.
+		return "", true, nil
+	}
+
+	if entry, found := idx.Entry(path); found && idx.Unchanged(entry, info) {
+		return entry.Hash, true, nil
+	}
+
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", false, err
+	}
+	b, err := db.NewBlob(content)
+	if err != nil {
+		return "", false, err
+	}
+	return b.Hash, true, nil
+}
+
+// fileInTheWay returns the closest parent directory of path that exists in
+// the work tree as something other than a directory, or "" if there is none.
+func fileInTheWay(repoRoot, path string) string {
+	for dir := filepath.Dir(filepath.FromSlash(path)); dir != "."; dir = filepath.Dir(dir) {
+		info, err := os.Lstat(filepath.Join(repoRoot, dir))
+		if err == nil && !info.IsDir() {
+			return filepath.ToSlash(dir)
+		}
+	}
+	return ""
+}
+
+func unionKeys(a, b map[string]string) map[string]bool {
+	keys := make(map[string]bool, len(a)+len(b))
+	for key := range a {
+		keys[key] = true
+	}
+	for key := range b {
+		keys[key] = true
+	}
+	return keys
+}
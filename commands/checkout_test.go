@@ -0,0 +1,193 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nexxeln/mini-git/refs"
+)
+
+// branchedRepo returns a repository with a feat branch that changes f and
+// adds dir/g, with master checked out.
+func branchedRepo(t *testing.T) string {
+	t.Helper()
+
+	repo := tempRepo(t)
+	commitFiles(t, repo, "base", map[string]string{"f": "base\n", "same": "same\n"})
+	if err := Checkout(repo, []string{"-b", "feat"}, johnDoe); err != nil {
+		t.Fatalf("Failed to create feat: %v", err)
+	}
+	commitFiles(t, repo, "feat", map[string]string{"f": "feat\n", "dir/g": "g\n"})
+	if err := Checkout(repo, []string{"master"}, johnDoe); err != nil {
+		t.Fatalf("Failed to check out master: %v", err)
+	}
+	return repo
+}
+
+func TestCheckoutSwitchesFilesAndIndex(t *testing.T) {
+	repo := branchedRepo(t)
+	if _, err := os.Stat(filepath.Join(repo, "dir")); !os.IsNotExist(err) {
+		t.Errorf("Expected dir to be removed when leaving feat")
+	}
+
+	if err := Checkout(repo, []string{"feat"}, johnDoe); err != nil {
+		t.Fatalf("Failed to check out feat: %v", err)
+	}
+	if got := readFile(t, repo, "f"); got != "feat\n" {
+		t.Errorf("Expected f from feat, got %q", got)
+	}
+	if got := readFile(t, repo, "dir/g"); got != "g\n" {
+		t.Errorf("Expected dir/g from feat, got %q", got)
+	}
+	if stagedHash(t, repo, "dir/g") == "" {
+		t.Errorf("Expected dir/g to be in the index")
+	}
+}
+
+func TestCheckoutRefusesToLoseLocalChanges(t *testing.T) {
+	repo := branchedRepo(t)
+	writeFile(t, repo, "f", "local\n")
+
+	err := Checkout(repo, []string{"feat"}, johnDoe)
+	if err == nil || !strings.Contains(err.Error(), "f") {
+		t.Fatalf("Expected checkout to refuse and name f, got %v", err)
+	}
+	if got := readFile(t, repo, "f"); got != "local\n" {
+		t.Errorf("Expected the local change to survive, got %q", got)
+	}
+	if branch, _ := getCurrentBranch(repo); branch != "master" {
+		t.Errorf("Expected to stay on master, got %s", branch)
+	}
+
+	if err := Checkout(repo, []string{"--force", "feat"}, johnDoe); err != nil {
+		t.Fatalf("Failed to force checkout: %v", err)
+	}
+	if got := readFile(t, repo, "f"); got != "feat\n" {
+		t.Errorf("Expected --force to discard the local change, got %q", got)
+	}
+}
+
+func TestCheckoutRefusesToOverwriteUntrackedFiles(t *testing.T) {
+	repo := branchedRepo(t)
+	writeFile(t, repo, "dir/g", "mine\n")
+
+	err := Checkout(repo, []string{"feat"}, johnDoe)
+	if err == nil || !strings.Contains(err.Error(), "untracked") || !strings.Contains(err.Error(), "dir/g") {
+		t.Fatalf("Expected checkout to refuse and name dir/g, got %v", err)
+	}
+	if got := readFile(t, repo, "dir/g"); got != "mine\n" {
+		t.Errorf("Expected the untracked file to survive, got %q", got)
+	}
+}
+
+func TestCheckoutReportsFileInPlaceOfDirectory(t *testing.T) {
+	repo := branchedRepo(t)
+	writeFile(t, repo, "dir", "a file\n")
+
+	err := Checkout(repo, []string{"feat"}, johnDoe)
+	if err == nil || !strings.Contains(err.Error(), "untracked working tree files would be overwritten by checkout:\n\tdir\n") {
+		t.Fatalf("Expected checkout to name the untracked file dir, got %v", err)
+	}
+	if got := readFile(t, repo, "dir"); got != "a file\n" {
+		t.Errorf("Expected the untracked file to survive, got %q", got)
+	}
+}
+
+func TestCheckoutKeepsUntrackedAndUnchangedFiles(t *testing.T) {
+	repo := branchedRepo(t)
+	writeFile(t, repo, "notes", "untracked\n")
+	writeFile(t, repo, "same", "edited\n")
+
+	old := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(filepath.Join(repo, "same"), old, old); err != nil {
+		t.Fatalf("Failed to set mtime: %v", err)
+	}
+
+	if err := Checkout(repo, []string{"feat"}, johnDoe); err != nil {
+		t.Fatalf("Failed to check out feat: %v", err)
+	}
+	if got := readFile(t, repo, "notes"); got != "untracked\n" {
+		t.Errorf("Expected the untracked file to survive, got %q", got)
+	}
+	if got := readFile(t, repo, "same"); got != "edited\n" {
+		t.Errorf("Expected the change to an unchanged path to carry over, got %q", got)
+	}
+	info, err := os.Stat(filepath.Join(repo, "same"))
+	if err != nil || !info.ModTime().Equal(old) {
+		t.Errorf("Expected a path that does not differ to be left untouched")
+	}
+}
+
+func TestCheckoutDetachesAtCommit(t *testing.T) {
+	repo := tempRepo(t)
+	first := commitFiles(t, repo, "one", map[string]string{"f": "one\n"})
+	commitFiles(t, repo, "two", map[string]string{"f": "two\n"})
+	if err := Tag(repo, []string{"v1", first}, nil); err != nil {
+		t.Fatalf("Failed to tag: %v", err)
+	}
+
+	if err := Checkout(repo, []string{"v1"}, johnDoe); err != nil {
+		t.Fatalf("Failed to check out v1: %v", err)
+	}
+	ref, hash, err := refs.Head(repo)
+	if err != nil || ref != "" || hash != first {
+		t.Errorf("Expected HEAD detached at %s, got %q %s (%v)", first, ref, hash, err)
+	}
+	if got := readFile(t, repo, "f"); got != "one\n" {
+		t.Errorf("Expected f from v1, got %q", got)
+	}
+}
+
+func TestCheckoutWarnsAboutLeftBehindCommits(t *testing.T) {
+	repo := tempRepo(t)
+	commitFiles(t, repo, "one", map[string]string{"f": "one\n"})
+	if err := Checkout(repo, []string{"HEAD"}, johnDoe); err != nil {
+		t.Fatalf("Failed to detach HEAD: %v", err)
+	}
+	detached := commitFiles(t, repo, "detached work", map[string]string{"f": "detached\n"})
+
+	output := captureOutput(t, func() {
+		if err := Checkout(repo, []string{"master"}, johnDoe); err != nil {
+			t.Errorf("Failed to check out master: %v", err)
+		}
+	})
+	if !strings.Contains(output, "leaving 1 commit behind") || !strings.Contains(output, shortHash(detached)+" detached work") {
+		t.Errorf("Expected a warning about the detached commit, got:\n%s", output)
+	}
+
+	output = captureOutput(t, func() {
+		if err := Checkout(repo, []string{"HEAD"}, johnDoe); err != nil {
+			t.Errorf("Failed to detach HEAD: %v", err)
+		}
+		if err := Checkout(repo, []string{"master"}, johnDoe); err != nil {
+			t.Errorf("Failed to check out master: %v", err)
+		}
+	})
+	if strings.Contains(output, "Warning") {
+		t.Errorf("Expected no warning when no commits are left behind, got:\n%s", output)
+	}
+}
+
+func TestCheckoutNewBranch(t *testing.T) {
+	repo := tempRepo(t)
+	first := commitFiles(t, repo, "one", map[string]string{"f": "one\n"})
+	commitFiles(t, repo, "two", map[string]string{"f": "two\n"})
+
+	if err := Checkout(repo, []string{"-b", "topic", "HEAD~1"}, johnDoe); err != nil {
+		t.Fatalf("Failed to check out a new branch: %v", err)
+	}
+	ref, hash, err := refs.Head(repo)
+	if err != nil || ref != "refs/heads/topic" || hash != first {
+		t.Errorf("Expected HEAD on topic at %s, got %q %s (%v)", first, ref, hash, err)
+	}
+	if got := readFile(t, repo, "f"); got != "one\n" {
+		t.Errorf("Expected f from the start point, got %q", got)
+	}
+
+	if err := Checkout(repo, []string{"-b", "topic"}, johnDoe); err == nil {
+		t.Errorf("Expected creating an existing branch to fail")
+	}
+}
@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nexxeln/mini-git/identity"
+	"github.com/nexxeln/mini-git/refs"
+)
+
+var johnDoe = identity.Identity{Name: "John Doe", Email: "john@example.com", When: time.Unix(1625140800, 0).UTC()}
+
+// tempRepo initializes a repository in a temporary directory, isolated from
+// the user's config.
+func tempRepo(t *testing.T) string {
+	t.Helper()
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("MINI_GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("MINI_GIT_CONFIG_GLOBAL", "")
+
+	repo := t.TempDir()
+	if err := Init(repo, nil); err != nil {
+		t.Fatalf("Failed to initialize repository: %v", err)
+	}
+	return repo
+}
+
+func writeFile(t *testing.T, repo, name, content string) {
+	t.Helper()
+
+	path := filepath.Join(repo, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory for %s: %v", name, err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
+func readFile(t *testing.T, repo, name string) string {
+	t.Helper()
+
+	data, err := os.ReadFile(filepath.Join(repo, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatalf("Failed to read %s: %v", name, err)
+	}
+	return string(data)
+}
+
+// commitFiles writes and stages the given files, then commits them.
+func commitFiles(t *testing.T, repo, message string, files map[string]string) string {
+	t.Helper()
+
+	var paths []string
+	for name, content := range files {
+		writeFile(t, repo, name, content)
+		paths = append(paths, name)
+	}
+	if err := Add(repo, paths); err != nil {
+		t.Fatalf("Failed to add files: %v", err)
+	}
+	if err := Commit(repo, message, johnDoe, johnDoe); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	return headHash(t, repo)
+}
+
+func headHash(t *testing.T, repo string) string {
+	t.Helper()
+
+	_, hash, err := refs.Head(repo)
+	if err != nil {
+		t.Fatalf("Failed to read HEAD: %v", err)
+	}
+	return hash
+}
+
+func stagedHash(t *testing.T, repo, path string) string {
+	t.Helper()
+
+	files, err := readIndex(repo)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	return files[path]
+}
+
+// captureOutput returns what fn prints to standard output.
+func captureOutput(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+	fn()
+	w.Close()
+	return <-output
+}
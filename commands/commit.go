@@ -1,47 +1,34 @@
 package commands
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/nexxeln/mini-git/commit"
-	"github.com/nexxeln/mini-git/objects"
-	"github.com/nexxeln/mini-git/repository"
-	"github.com/nexxeln/mini-git/tree"
+	"github.com/nexxeln/mini-git/identity"
+	"github.com/nexxeln/mini-git/refs"
 )
 
-func Commit(startPath, message, author string) error {
-	repoRoot, err := repository.FindRoot(startPath)
+func Commit(startPath, message string, author, committer identity.Identity) error {
+	r, err := OpenRepo(startPath)
 	if err != nil {
-		return fmt.Errorf("not a mini-git repository (or any of the parent directories): %v", err)
+		return err
 	}
+	return r.Commit(message, author, committer)
+}
 
-	indexPath := filepath.Join(repoRoot, ".mini-git", "index")
-	indexFile, err := os.Open(indexPath)
-	if err != nil {
-		return fmt.Errorf("failed to open index file: %v", err)
-	}
-	defer indexFile.Close()
-
-	rootTree := tree.NewTree()
-	scanner := bufio.NewScanner(indexFile)
-	for scanner.Scan() {
-		parts := strings.Fields(scanner.Text())
-		if len(parts) != 2 {
-			return fmt.Errorf("invalid index entry: %s", scanner.Text())
-		}
-		hash, path := parts[0], parts[1]
-		rootTree.AddEntry(path, hash, tree.EntryTypeBlob)
-	}
+func (r *Repo) Commit(message string, author, committer identity.Identity) error {
+	repoRoot, db := r.Root, r.DB
 
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading index file: %v", err)
+	files, err := readIndex(repoRoot)
+	if err != nil {
+		return fmt.Errorf("failed to read index: %v", err)
 	}
 
-	if err := objects.Store(repoRoot, rootTree); err != nil {
+	treeHash, err := writeTree(db, files)
+	if err != nil {
 		return fmt.Errorf("failed to store root tree: %v", err)
 	}
 
@@ -63,21 +50,53 @@ func Commit(startPath, message, author string) error {
 		parentHash = currentRef
 	}
 
-	newCommit := commit.NewCommit(rootTree.Hash(), parentHash, author, author, message)
-	if err := objects.Store(repoRoot, newCommit); err != nil {
+	var parentHashes []string
+	if parentHash != "" {
+		parentHashes = append(parentHashes, parentHash)
+	}
+
+	// A pending merge records the merged commit as an additional parent.
+	mergeHeadPath := filepath.Join(repoRoot, ".mini-git", "MERGE_HEAD")
+	mergeHead, err := os.ReadFile(mergeHeadPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read MERGE_HEAD: %v", err)
+	}
+	if mergeHash := strings.TrimSpace(string(mergeHead)); mergeHash != "" {
+		parentHashes = append(parentHashes, mergeHash)
+	}
+
+	newCommit := commit.NewCommit(treeHash, parentHashes, author.String(), committer.String(), message)
+	newCommit.AuthorDate = author.When
+	newCommit.CommitDate = committer.When
+	commitHash, err := db.Put(newCommit)
+	if err != nil {
 		return fmt.Errorf("failed to store commit: %v", err)
 	}
 
+	ref := "HEAD"
 	if strings.HasPrefix(currentRef, "ref: ") {
-		refPath := filepath.Join(repoRoot, ".mini-git", strings.TrimPrefix(currentRef, "ref: "))
-		if err := os.WriteFile(refPath, []byte(newCommit.Hash()), 0644); err != nil {
-			return fmt.Errorf("failed to update ref file: %v", err)
-		}
-	} else {
-		if err := os.WriteFile(headPath, []byte(newCommit.Hash()), 0644); err != nil {
-			return fmt.Errorf("failed to update HEAD: %v", err)
-		}
+		ref = strings.TrimPrefix(currentRef, "ref: ")
+	}
+	if err := refs.Update(repoRoot, ref, commitHash, committer, commitLogMessage(parentHashes, message)); err != nil {
+		return err
+	}
+
+	if err := os.Remove(mergeHeadPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove MERGE_HEAD: %v", err)
 	}
 
 	return nil
 }
+
+// commitLogMessage describes a new commit for the reflog the way Git does,
+// using the first line of its message.
+func commitLogMessage(parentHashes []string, message string) string {
+	subject, _, _ := strings.Cut(message, "\n")
+	switch {
+	case len(parentHashes) == 0:
+		return "commit (initial): " + subject
+	case len(parentHashes) > 1:
+		return "commit (merge): " + subject
+	}
+	return "commit: " + subject
+}
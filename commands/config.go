@@ -0,0 +1,188 @@
+package commands
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nexxeln/mini-git/config"
+	"github.com/nexxeln/mini-git/objects"
+	"github.com/nexxeln/mini-git/repository"
+)
+
+const configUsage = "usage: mini-git config [--system | --global | --local | -f <file>] [--type=<bool|int|path>] (--get <name> | --get-all <name> | --set <name> <value> | --unset <name> | --unset-all <name> | --list | <name> [<value>])"
+
+// Config reads and writes configuration. Reads see the system, global and
+// repository files combined unless a scope or file is given; writes go to
+// the repository's file by default. --type checks values and prints or
+// stores them in canonical form.
+func Config(startPath string, args []string) error {
+	var scope *config.Scope
+	file := ""
+	action := ""
+	valueType := ""
+	var operands []string
+	scopes := map[string]config.Scope{"--system": config.System, "--global": config.Global, "--local": config.Local}
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--system" || arg == "--global" || arg == "--local":
+			s := scopes[arg]
+			if scope != nil && *scope != s {
+				return fmt.Errorf(configUsage)
+			}
+			scope = &s
+		case arg == "-f" || arg == "--file":
+			if i+1 == len(args) || file != "" {
+				return fmt.Errorf(configUsage)
+			}
+			i++
+			file = args[i]
+		case strings.HasPrefix(arg, "--file="):
+			if file != "" {
+				return fmt.Errorf(configUsage)
+			}
+			file = strings.TrimPrefix(arg, "--file=")
+		case arg == "--bool" || arg == "--int" || arg == "--path" || strings.HasPrefix(arg, "--type="):
+			t := strings.TrimPrefix(strings.TrimPrefix(arg, "--type="), "--")
+			if t != "bool" && t != "int" && t != "path" || valueType != "" && valueType != t {
+				return fmt.Errorf(configUsage)
+			}
+			valueType = t
+		case arg == "-l" || arg == "--list" || arg == "--get" || arg == "--get-all" || arg == "--set" || arg == "--unset" || arg == "--unset-all":
+			if arg == "-l" {
+				arg = "--list"
+			}
+			if action != "" && action != arg {
+				return fmt.Errorf(configUsage)
+			}
+			action = arg
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf(configUsage)
+		default:
+			operands = append(operands, arg)
+		}
+	}
+
+	if action == "" {
+		switch len(operands) {
+		case 1:
+			action = "--get"
+		case 2:
+			action = "--set"
+		default:
+			return fmt.Errorf(configUsage)
+		}
+	}
+
+	want := map[string]int{"--get": 1, "--get-all": 1, "--set": 2, "--unset": 1, "--unset-all": 1, "--list": 0}
+	if len(operands) != want[action] || scope != nil && file != "" {
+		return fmt.Errorf(configUsage)
+	}
+
+	repoRoot, _ := repository.FindRoot(startPath)
+
+	path := file
+	if scope != nil {
+		var err error
+		path, err = scope.Path(repoRoot)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch action {
+	case "--set", "--unset", "--unset-all":
+		if path == "" {
+			if repoRoot == "" {
+				return fmt.Errorf("not in a mini-git repository; use --global or --file to choose a config file")
+			}
+			path, _ = config.Local.Path(repoRoot)
+		}
+		value := ""
+		if action == "--set" {
+			var err error
+			value, err = canonicalValue(operands[1], valueType)
+			if err != nil {
+				return err
+			}
+		}
+		if repoRoot != "" && samePath(path, filepath.Join(repoRoot, ".mini-git", "config")) {
+			if err := objects.CheckSettingChange(repoRoot, operands[0], value); err != nil {
+				return err
+			}
+		}
+		switch action {
+		case "--set":
+			return config.Set(path, operands[0], value)
+		case "--unset":
+			return config.Unset(path, operands[0])
+		default:
+			return config.UnsetAll(path, operands[0])
+		}
+	}
+
+	var c *config.Config
+	var err error
+	if path == "" {
+		c, err = config.LoadAll(repoRoot)
+	} else {
+		c, err = config.Load(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	if action == "--list" {
+		for _, entry := range c.Entries() {
+			fmt.Printf("%s=%s\n", entry.Name, entry.Value)
+		}
+		return nil
+	}
+
+	values := c.GetAll(operands[0])
+	if len(values) == 0 {
+		return fmt.Errorf("%s is not set", operands[0])
+	}
+	if action == "--get" {
+		values = values[len(values)-1:]
+	}
+	for _, value := range values {
+		value, err := canonicalValue(value, valueType)
+		if err != nil {
+			return fmt.Errorf("%s: %v", operands[0], err)
+		}
+		fmt.Println(value)
+	}
+	return nil
+}
+
+// samePath reports whether two paths name the same file, which need not
+// exist yet.
+func samePath(a, b string) bool {
+	a, errA := filepath.Abs(a)
+	b, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && a == b
+}
+
+// canonicalValue checks value against a --type and returns it in canonical
+// form.
+func canonicalValue(value, valueType string) (string, error) {
+	switch valueType {
+	case "bool":
+		b, err := config.ParseBool(value)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatBool(b), nil
+	case "int":
+		n, err := config.ParseInt(value)
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(n, 10), nil
+	case "path":
+		return config.ExpandPath(value)
+	}
+	return value, nil
+}
@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"testing"
+)
+
+func TestConfigKeepsFormatOnceObjectsExist(t *testing.T) {
+	repo := tempRepo(t)
+	if err := Config(repo, []string{"core.treeencoding", "binary"}); err != nil {
+		t.Fatalf("Expected an empty repository to allow a new tree encoding: %v", err)
+	}
+	if err := Config(repo, []string{"--unset", "core.treeencoding"}); err != nil {
+		t.Fatalf("Failed to unset the tree encoding: %v", err)
+	}
+
+	commitFiles(t, repo, "one", map[string]string{"dir/f": "one\n"})
+
+	if err := Config(repo, []string{"core.treeencoding", "binary"}); err == nil {
+		t.Errorf("Expected changing the tree encoding to fail")
+	}
+	if err := Config(repo, []string{"--local", "core.objectformat", "git"}); err == nil {
+		t.Errorf("Expected changing the object format to fail")
+	}
+	if err := Config(repo, []string{"core.treeencoding", "text"}); err != nil {
+		t.Errorf("Expected setting the current tree encoding to succeed: %v", err)
+	}
+	if err := Status(repo); err != nil {
+		t.Errorf("Expected status to keep working: %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nexxeln/mini-git/diff"
+	"github.com/nexxeln/mini-git/objects"
+	"github.com/nexxeln/mini-git/revision"
+)
+
+// diffSide is one side of a comparison: the blob hash of every file, plus
+// contents that are not in the object store, such as working tree files.
+type diffSide struct {
+	hashes   map[string]string
+	contents map[string][]byte
+}
+
+func Diff(startPath string, args []string) error {
+	r, err := OpenRepo(startPath)
+	if err != nil {
+		return err
+	}
+	return r.Diff(args)
+}
+
+func (r *Repo) Diff(args []string) error {
+	repoRoot, db := r.Root, r.DB
+
+	opts, cached, revisions, err := parseDiffArgs(args)
+	if err != nil {
+		return err
+	}
+
+	var oldSide, newSide *diffSide
+	switch {
+	case len(revisions) == 0 && !cached:
+		// Working tree against the index
+		oldSide, err = indexSide(repoRoot)
+		if err != nil {
+			return err
+		}
+		newSide, err = workingTreeSide(repoRoot, db, oldSide.hashes)
+		if err != nil {
+			return err
+		}
+
+	case len(revisions) == 0 && cached:
+		// Index against HEAD
+		headHash, err := getHEADCommitHash(repoRoot)
+		if err != nil {
+			return fmt.Errorf("failed to get HEAD commit hash: %v", err)
+		}
+		oldSide, err = commitSide(db, headHash)
+		if err != nil {
+			return err
+		}
+		newSide, err = indexSide(repoRoot)
+		if err != nil {
+			return err
+		}
+
+	case len(revisions) == 2 && !cached:
+		oldHash, err := revision.ResolveCommit(repoRoot, db, revisions[0])
+		if err != nil {
+			return err
+		}
+		newHash, err := revision.ResolveCommit(repoRoot, db, revisions[1])
+		if err != nil {
+			return err
+		}
+		oldSide, err = commitSide(db, oldHash)
+		if err != nil {
+			return err
+		}
+		newSide, err = commitSide(db, newHash)
+		if err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("usage: mini-git diff [<options>] [--cached | <commit> <commit>]")
+	}
+
+	return printDiff(db, oldSide, newSide, opts)
+}
+
+func parseDiffArgs(args []string) (diff.Options, bool, []string, error) {
+	opts := diff.DefaultOptions()
+	cached := false
+	var revisions []string
+
+	for _, arg := range args {
+		switch {
+		case arg == "--cached" || arg == "--staged":
+			cached = true
+		case arg == "--patience":
+			opts.Algorithm = diff.AlgorithmPatience
+		case arg == "--histogram":
+			opts.Algorithm = diff.AlgorithmHistogram
+		case strings.HasPrefix(arg, "--diff-algorithm="):
+			algorithm, err := diff.ParseAlgorithm(strings.TrimPrefix(arg, "--diff-algorithm="))
+			if err != nil {
+				return opts, false, nil, err
+			}
+			opts.Algorithm = algorithm
+		case strings.HasPrefix(arg, "--unified=") || strings.HasPrefix(arg, "-U"):
+			value := strings.TrimPrefix(strings.TrimPrefix(arg, "--unified="), "-U")
+			context, err := strconv.Atoi(value)
+			if err != nil || context < 0 {
+				return opts, false, nil, fmt.Errorf("invalid number of context lines: %s", value)
+			}
+			opts.Context = context
+		case arg == "-w" || arg == "--ignore-all-space":
+			opts.Whitespace = diff.WhitespaceIgnoreAll
+		case arg == "-b" || arg == "--ignore-space-change":
+			opts.Whitespace = diff.WhitespaceIgnoreChange
+		case arg == "--ignore-space-at-eol":
+			opts.Whitespace = diff.WhitespaceIgnoreAtEOL
+		case strings.HasPrefix(arg, "-"):
+			return opts, false, nil, fmt.Errorf("unknown diff option: %s", arg)
+		default:
+			revisions = append(revisions, arg)
+		}
+	}
+
+	return opts, cached, revisions, nil
+}
+
+func indexSide(repoRoot string) (*diffSide, error) {
+	files, err := readIndex(repoRoot)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read index: %v", err)
+	}
+	return &diffSide{hashes: files}, nil
+}
+
+func commitSide(db *objects.Database, commitHash string) (*diffSide, error) {
+	files, err := readCommitTree(db, commitHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree of commit %s: %v", commitHash, err)
+	}
+	return &diffSide{hashes: files}, nil
+}
+
+// workingTreeSide reads the working tree copies of the tracked files. Files
+// missing from disk are left out so they show up as deletions.
+func workingTreeSide(repoRoot string, db *objects.Database, tracked map[string]string) (*diffSide, error) {
+	side := &diffSide{
+		hashes:   make(map[string]string),
+		contents: make(map[string][]byte),
+	}
+
+	for path := range tracked {
+		content, err := os.ReadFile(filepath.Join(repoRoot, path))
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, fmt.Errorf("failed to read %s: %v", path, err)
+		}
+
+		b, err := db.NewBlob(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create blob for %s: %v", path, err)
+		}
+		side.hashes[path] = b.Hash
+		side.contents[path] = content
+	}
+
+	return side, nil
+}
+
+func (s *diffSide) content(db *objects.Database, path string) ([]byte, error) {
+	if content, exists := s.contents[path]; exists {
+		return content, nil
+	}
+	return blobContent(db, s.hashes[path])
+}
+
+func printDiff(db *objects.Database, oldSide, newSide *diffSide, opts diff.Options) error {
+	paths := make(map[string]bool)
+	for path := range oldSide.hashes {
+		paths[path] = true
+	}
+	for path := range newSide.hashes {
+		paths[path] = true
+	}
+
+	for _, path := range sortedKeys(paths) {
+		oldHash, newHash := oldSide.hashes[path], newSide.hashes[path]
+		if oldHash == newHash {
+			continue
+		}
+
+		oldContent, err := oldSide.content(db, path)
+		if err != nil {
+			return fmt.Errorf("failed to read old version of %s: %v", path, err)
+		}
+		newContent, err := newSide.content(db, path)
+		if err != nil {
+			return fmt.Errorf("failed to read new version of %s: %v", path, err)
+		}
+
+		oldName, newName := "a/"+path, "b/"+path
+		fmt.Printf("diff --mini-git %s %s\n", oldName, newName)
+		switch {
+		case oldHash == "":
+			fmt.Println("new file mode 100644")
+			oldName = "/dev/null"
+		case newHash == "":
+			fmt.Println("deleted file mode 100644")
+			newName = "/dev/null"
+		}
+		fmt.Printf("index %s..%s\n", shortHash(oldHash), shortHash(newHash))
+
+		if err := diff.Unified(os.Stdout, oldName, newName, oldContent, newContent, opts); err != nil {
+			return fmt.Errorf("failed to write diff for %s: %v", path, err)
+		}
+	}
+
+	return nil
+}
+
+func shortHash(hash string) string {
+	if hash == "" {
+		return "0000000"
+	}
+	return hash[:7]
+}
@@ -0,0 +1,207 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nexxeln/mini-git/objects"
+	"github.com/nexxeln/mini-git/refs"
+	"github.com/nexxeln/mini-git/tree"
+)
+
+// Gc packs every reachable object into a single packfile and removes the
+// loose copies and older packs. Unreachable objects are kept: loose ones are
+// left alone and packed ones are moved out of the old packs as loose objects
+// before those are removed. Only repositories whose objects are stored on
+// disk can be packed.
+func Gc(startPath string, args []string) error {
+	r, err := OpenRepo(startPath)
+	if err != nil {
+		return err
+	}
+	return r.Gc(args)
+}
+
+func (r *Repo) Gc(args []string) error {
+	repoRoot, db := r.Root, r.DB
+
+	if len(args) != 0 {
+		return fmt.Errorf("usage: mini-git gc")
+	}
+	// Packing reads and deletes the files of the object directory itself.
+	if _, ok := db.Store.(*objects.FileStore); !ok {
+		return fmt.Errorf("gc needs a repository whose objects are stored on disk")
+	}
+
+	roots, err := gcRoots(repoRoot)
+	if err != nil {
+		return err
+	}
+
+	reachable := make(map[string]bool)
+	for _, hash := range roots {
+		if err := markObject(db, hash, reachable); err != nil {
+			return err
+		}
+	}
+
+	index, err := readIndex(repoRoot)
+	if err != nil {
+		return err
+	}
+	for _, hash := range index {
+		reachable[hash] = true
+	}
+
+	if len(reachable) == 0 {
+		fmt.Println("Nothing to pack")
+		return nil
+	}
+
+	hashes := sortedKeys(reachable)
+	result, err := objects.WritePack(repoRoot, hashes)
+	if err != nil {
+		return fmt.Errorf("failed to write pack: %v", err)
+	}
+
+	if _, err := objects.UnpackObjects(repoRoot, reachable); err != nil {
+		return fmt.Errorf("failed to unpack unreachable objects: %v", err)
+	}
+	if err := objects.RemovePacks(repoRoot, result.Name); err != nil {
+		return fmt.Errorf("failed to remove old packs: %v", err)
+	}
+	if err := objects.RemoveLooseObjects(repoRoot, hashes); err != nil {
+		return fmt.Errorf("failed to remove loose objects: %v", err)
+	}
+
+	fmt.Printf("Packed %d objects (%d deltas) into %s\n", result.Objects, result.Deltas, result.Name)
+	return nil
+}
+
+// gcRoots returns the objects that branches, tags, HEAD, an in-progress
+// merge and reflog entries point at.
+func gcRoots(repoRoot string) ([]string, error) {
+	var roots []string
+
+	allRefs, err := refs.List(repoRoot, "refs/")
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range allRefs {
+		hash, err := refs.Read(repoRoot, ref)
+		if err != nil {
+			return nil, err
+		}
+		roots = append(roots, hash)
+	}
+
+	headHash, err := getHEADCommitHash(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	if headHash != "" {
+		roots = append(roots, headHash)
+	}
+
+	mergeHead, err := os.ReadFile(filepath.Join(repoRoot, ".mini-git", "MERGE_HEAD"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to read MERGE_HEAD: %v", err)
+	}
+	if hash := strings.TrimSpace(string(mergeHead)); hash != "" {
+		roots = append(roots, hash)
+	}
+
+	logs, err := refs.ListLogs(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	for _, ref := range logs {
+		entries, err := refs.ReadLog(repoRoot, ref)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			for _, hash := range []string{entry.OldHash, entry.NewHash} {
+				if hash != refs.ZeroHash {
+					roots = append(roots, hash)
+				}
+			}
+		}
+	}
+
+	return roots, nil
+}
+
+// markObject marks hash and everything it refers to, peeling annotated tags.
+func markObject(db *objects.Database, hash string, reachable map[string]bool) error {
+	for hash != "" && !reachable[hash] {
+		kind, _, err := db.ReadRaw(hash)
+		if err != nil {
+			return fmt.Errorf("failed to read object %s: %v", hash, err)
+		}
+
+		switch kind {
+		case objects.TypeCommit:
+			return markCommit(db, hash, reachable)
+		case objects.TypeTree:
+			return markTree(db, hash, reachable)
+		case objects.TypeTag:
+			t, err := db.Tag(hash)
+			if err != nil {
+				return fmt.Errorf("failed to retrieve tag %s: %v", hash, err)
+			}
+			reachable[hash] = true
+			hash = t.ObjectHash
+		default:
+			reachable[hash] = true
+		}
+	}
+	return nil
+}
+
+func markCommit(db *objects.Database, hash string, reachable map[string]bool) error {
+	pending := []string{hash}
+	for len(pending) > 0 {
+		hash := pending[len(pending)-1]
+		pending = pending[:len(pending)-1]
+		if hash == "" || reachable[hash] {
+			continue
+		}
+		reachable[hash] = true
+
+		commit, err := db.Commit(hash)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve commit %s: %v", hash, err)
+		}
+		if err := markTree(db, commit.TreeHash, reachable); err != nil {
+			return err
+		}
+		pending = append(pending, commit.ParentHashes...)
+	}
+	return nil
+}
+
+func markTree(db *objects.Database, hash string, reachable map[string]bool) error {
+	if reachable[hash] {
+		return nil
+	}
+	reachable[hash] = true
+
+	t, err := db.Tree(hash)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve tree %s: %v", hash, err)
+	}
+
+	for _, entry := range t.Entries {
+		if entry.Type == tree.EntryTypeTree {
+			if err := markTree(db, entry.Hash, reachable); err != nil {
+				return err
+			}
+			continue
+		}
+		reachable[entry.Hash] = true
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGcTwice(t *testing.T) {
+	repo := tempRepo(t)
+	commitFiles(t, repo, "one", map[string]string{"f": "one\n"})
+	head := commitFiles(t, repo, "two", map[string]string{"f": "two\n"})
+
+	for i := 0; i < 2; i++ {
+		if err := Gc(repo, nil); err != nil {
+			t.Fatalf("gc %d failed: %v", i+1, err)
+		}
+	}
+
+	packs, err := filepath.Glob(filepath.Join(repo, ".mini-git", "objects", "pack", "pack-*.pack"))
+	if err != nil || len(packs) != 1 {
+		t.Fatalf("Expected one pack, got %v (%v)", packs, err)
+	}
+	if err := RevParse(repo, []string{"HEAD~1"}); err != nil {
+		t.Errorf("Expected history to survive gc: %v", err)
+	}
+	if headHash(t, repo) != head {
+		t.Errorf("Expected HEAD to be unchanged by gc")
+	}
+
+	if err := os.Remove(filepath.Join(repo, "f")); err != nil {
+		t.Fatalf("Failed to remove f: %v", err)
+	}
+	if err := Checkout(repo, []string{"--force", "master"}, johnDoe); err != nil {
+		t.Fatalf("Failed to check out from the pack: %v", err)
+	}
+	if got := readFile(t, repo, "f"); got != "two\n" {
+		t.Errorf("Expected f to be restored from the pack, got %q", got)
+	}
+}
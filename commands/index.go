@@ -0,0 +1,34 @@
+package commands
+
+import (
+	"github.com/nexxeln/mini-git/index"
+)
+
+// readIndex returns the staged files as a map of path to blob hash.
+func readIndex(repoRoot string) (map[string]string, error) {
+	idx, err := index.Read(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	return idx.Files(), nil
+}
+
+// writeIndex replaces the index with files. Entries whose hash is unchanged
+// keep their stat data; the others are hashed again the next time they are
+// compared.
+func writeIndex(repoRoot string, files map[string]string) error {
+	old, err := index.Read(repoRoot)
+	if err != nil {
+		return err
+	}
+
+	idx := &index.Index{}
+	for _, path := range sortedKeys(files) {
+		if entry, found := old.Entry(path); found && entry.Hash == files[path] {
+			idx.Add(entry)
+			continue
+		}
+		idx.Add(index.Entry{Path: path, Hash: files[path], Mode: 0100644})
+	}
+	return idx.Write(repoRoot)
+}
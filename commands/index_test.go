@@ -0,0 +1,67 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/nexxeln/mini-git/objects"
+)
+
+// checkIndexMatchesHead fails the test unless the index lists exactly the
+// files of HEAD's tree.
+func checkIndexMatchesHead(t *testing.T, repo string) {
+	t.Helper()
+
+	db, err := objects.Open(repo)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	files, err := readCommitTree(db, headHash(t, repo))
+	if err != nil {
+		t.Fatalf("Failed to read HEAD tree: %v", err)
+	}
+	staged, err := readIndex(repo)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if len(staged) != len(files) {
+		t.Errorf("Expected the index to hold %v, got %v", files, staged)
+	}
+	for path, hash := range files {
+		if staged[path] != hash {
+			t.Errorf("Expected %s staged as %s, got %q", path, hash, staged[path])
+		}
+	}
+}
+
+func TestCommitAfterCheckoutLeavesOtherBranchOut(t *testing.T) {
+	repo := branchedRepo(t)
+	checkIndexMatchesHead(t, repo)
+
+	commitFiles(t, repo, "master only", map[string]string{"h": "h\n"})
+	checkIndexMatchesHead(t, repo)
+	if stagedHash(t, repo, "dir/g") != "" {
+		t.Errorf("Expected dir/g from feat not to come back in a master commit")
+	}
+}
+
+func TestMergeUpdatesIndex(t *testing.T) {
+	repo := divergedRepo(t)
+	if err := Merge(repo, []string{"feat"}, johnDoe, johnDoe); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	checkIndexMatchesHead(t, repo)
+
+	fastForward := branchedRepo(t)
+	if err := Merge(fastForward, []string{"feat"}, johnDoe, johnDoe); err != nil {
+		t.Fatalf("Fast-forward merge failed: %v", err)
+	}
+	checkIndexMatchesHead(t, fastForward)
+}
+
+func TestResetHardUpdatesIndex(t *testing.T) {
+	repo, _, _, _ := twoCommitRepo(t)
+	if err := Reset(repo, []string{"--hard", "HEAD~1"}, johnDoe); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	checkIndexMatchesHead(t, repo)
+}
@@ -4,9 +4,30 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/nexxeln/mini-git/objects"
+	"github.com/nexxeln/mini-git/tree"
 )
 
-func Init(path string) error {
+func Init(path string, args []string) error {
+	format := objects.FormatMiniGit
+	encoding := tree.EncodingText
+	for _, arg := range args {
+		var err error
+		switch {
+		case strings.HasPrefix(arg, "--object-format="):
+			format, err = objects.ParseFormat(strings.TrimPrefix(arg, "--object-format="))
+		case strings.HasPrefix(arg, "--tree-encoding="):
+			encoding, err = tree.ParseEncoding(strings.TrimPrefix(arg, "--tree-encoding="))
+		default:
+			err = fmt.Errorf("usage: mini-git init [--object-format=mini-git|git] [--tree-encoding=text|binary]")
+		}
+		if err != nil {
+			return err
+		}
+	}
+
 	gitDir := filepath.Join(path, ".mini-git")
 
 	if err := os.MkdirAll(gitDir, 0755); err != nil {
@@ -37,6 +58,11 @@ func Init(path string) error {
 	filemode = false
 	bare = false
 `
+	if format == objects.FormatGit {
+		configContent += "\tobjectformat = git\n"
+	} else if encoding == tree.EncodingGit {
+		configContent += "\ttreeencoding = binary\n"
+	}
 	if err := os.WriteFile(configPath, []byte(configContent), 0644); err != nil {
 		return fmt.Errorf("failed to create config file: %v", err)
 	}
@@ -2,48 +2,87 @@ package commands
 
 import (
 	"fmt"
-	"os"
-	"path/filepath"
-	"strings"
 
-	"github.com/nexxeln/mini-git/objects"
-	"github.com/nexxeln/mini-git/repository"
+	"github.com/nexxeln/mini-git/commit"
+	"github.com/nexxeln/mini-git/revision"
 )
 
-func Log(startPath string) error {
-	repoRoot, err := repository.FindRoot(startPath)
+func Log(startPath string, args []string) error {
+	r, err := OpenRepo(startPath)
 	if err != nil {
-		return fmt.Errorf("not a mini-git repository (or any of the parent directories): %v", err)
+		return err
+	}
+	return r.Log(args)
+}
+
+func (r *Repo) Log(args []string) error {
+	repoRoot, db := r.Root, r.DB
+
+	if len(args) > 1 {
+		return fmt.Errorf("usage: mini-git log [<revision>]")
 	}
 
-	headPath := filepath.Join(repoRoot, ".mini-git", "HEAD")
-	headContent, err := os.ReadFile(headPath)
+	var currentRef string
+	var err error
+	if len(args) == 1 {
+		currentRef, err = revision.ResolveCommit(repoRoot, db, args[0])
+	} else {
+		currentRef, err = getHEADCommitHash(repoRoot)
+	}
 	if err != nil {
-		return fmt.Errorf("failed to read HEAD file: %v", err)
+		return err
 	}
 
-	currentRef := strings.TrimSpace(string(headContent))
-	if strings.HasPrefix(currentRef, "ref: ") {
-		refPath := filepath.Join(repoRoot, ".mini-git", strings.TrimPrefix(currentRef, "ref: "))
-		refContent, err := os.ReadFile(refPath)
-		if err != nil {
-			return fmt.Errorf("failed to read ref file %s: %v", refPath, err)
-		}
-		currentRef = strings.TrimSpace(string(refContent))
+	if currentRef == "" {
+		return nil
 	}
 
-	for currentRef != "" {
-		commit, err := objects.RetrieveCommit(repoRoot, currentRef)
-		if err != nil {
-			return fmt.Errorf("failed to retrieve commit %s: %v", currentRef, err)
+	// Walk every parent of every commit, always showing the most recent
+	// pending commit next so merged histories interleave by date.
+	seen := map[string]bool{currentRef: true}
+	pending := []string{currentRef}
+	commits := make(map[string]*commit.Commit)
+
+	for len(pending) > 0 {
+		for _, hash := range pending {
+			if _, loaded := commits[hash]; loaded {
+				continue
+			}
+			c, err := db.Commit(hash)
+			if err != nil {
+				return fmt.Errorf("failed to retrieve commit %s: %v", hash, err)
+			}
+			commits[hash] = c
 		}
 
-		fmt.Printf("commit %s\n", currentRef)
-		fmt.Printf("Author: %s\n", commit.Author)
-		fmt.Printf("Date: %s\n", commit.AuthorDate)
-		fmt.Printf("\n    %s\n\n", commit.Message)
+		newest := 0
+		for i, hash := range pending {
+			if commits[hash].CommitDate.After(commits[pending[newest]].CommitDate) {
+				newest = i
+			}
+		}
+		hash := pending[newest]
+		pending = append(pending[:newest], pending[newest+1:]...)
+		c := commits[hash]
 
-		currentRef = commit.ParentHash
+		fmt.Printf("commit %s\n", hash)
+		if len(c.ParentHashes) > 1 {
+			fmt.Printf("Merge:")
+			for _, parentHash := range c.ParentHashes {
+				fmt.Printf(" %s", parentHash[:7])
+			}
+			fmt.Println()
+		}
+		fmt.Printf("Author: %s\n", c.Author)
+		fmt.Printf("Date: %s\n", c.AuthorDate.Format("Mon Jan 2 15:04:05 2006 -0700"))
+		fmt.Printf("\n    %s\n\n", c.Message)
+
+		for _, parentHash := range c.ParentHashes {
+			if !seen[parentHash] {
+				seen[parentHash] = true
+				pending = append(pending, parentHash)
+			}
+		}
 	}
 
 	return nil
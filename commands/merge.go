@@ -4,109 +4,389 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/nexxeln/mini-git/history"
+	"github.com/nexxeln/mini-git/identity"
+	"github.com/nexxeln/mini-git/index"
+	"github.com/nexxeln/mini-git/merge"
 	"github.com/nexxeln/mini-git/objects"
-	"github.com/nexxeln/mini-git/repository"
+	"github.com/nexxeln/mini-git/refs"
+	"github.com/nexxeln/mini-git/revision"
 )
 
-func Merge(startPath string, args []string) error {
-	if len(args) != 1 {
-		return fmt.Errorf("usage: mini-git merge <branch-name>")
+func Merge(startPath string, args []string, author, committer identity.Identity) error {
+	r, err := OpenRepo(startPath)
+	if err != nil {
+		return err
 	}
+	return r.Merge(args, author, committer)
+}
 
-	repoRoot, err := repository.FindRoot(startPath)
-	if err != nil {
-		return fmt.Errorf("not a mini-git repository (or any of the parent directories): %v", err)
+func (r *Repo) Merge(args []string, author, committer identity.Identity) error {
+	repoRoot, db := r.Root, r.DB
+
+	if len(args) != 1 {
+		return fmt.Errorf("usage: mini-git merge <commit>")
 	}
 
 	branchToMerge := args[0]
-	return mergeBranch(repoRoot, branchToMerge)
+	return mergeBranch(repoRoot, db, branchToMerge, author, committer)
 }
 
-func mergeBranch(repoRoot, branchToMerge string) error {
-	currentBranch, err := getCurrentBranch(repoRoot)
-	if err != nil {
-		return fmt.Errorf("failed to get current branch: %v", err)
+func mergeBranch(repoRoot string, db *objects.Database, branchToMerge string, author, committer identity.Identity) error {
+	mergeHeadPath := filepath.Join(repoRoot, ".mini-git", "MERGE_HEAD")
+	if _, err := os.Stat(mergeHeadPath); err == nil {
+		return fmt.Errorf("you have not concluded your merge (MERGE_HEAD exists); commit the result or reset before merging again")
 	}
 
-	currentCommitHash, err := getCommitHash(repoRoot, currentBranch)
+	headRef, currentCommitHash, err := refs.Head(repoRoot)
 	if err != nil {
 		return fmt.Errorf("failed to get current commit hash: %v", err)
 	}
+	if currentCommitHash == "" {
+		return fmt.Errorf("cannot merge into a branch without commits")
+	}
 
-	mergeCommitHash, err := getCommitHash(repoRoot, branchToMerge)
+	// A detached HEAD is updated directly and named HEAD in messages.
+	ref, currentBranch := "HEAD", "HEAD"
+	if headRef != "" {
+		ref, currentBranch = headRef, strings.TrimPrefix(headRef, "refs/heads/")
+	}
+
+	mergeCommitHash, err := revision.ResolveCommit(repoRoot, db, branchToMerge)
 	if err != nil {
-		return fmt.Errorf("failed to get merge commit hash: %v", err)
+		return err
+	}
+
+	upToDate, err := history.IsAncestor(db, mergeCommitHash, currentCommitHash)
+	if err != nil {
+		return fmt.Errorf("failed to check ancestry: %v", err)
+	}
+
+	if upToDate {
+		fmt.Println("Already up to date.")
+		return nil
 	}
 
 	// Check if it's a fast-forward merge
-	isAncestor, err := isAncestor(repoRoot, currentCommitHash, mergeCommitHash)
+	isAncestor, err := history.IsAncestor(db, currentCommitHash, mergeCommitHash)
 	if err != nil {
 		return fmt.Errorf("failed to check ancestry: %v", err)
 	}
 
 	if isAncestor {
-		return fastForwardMerge(repoRoot, currentBranch, branchToMerge, mergeCommitHash)
+		return fastForwardMerge(repoRoot, db, ref, currentBranch, branchToMerge, currentCommitHash, mergeCommitHash, committer)
 	}
 
-	return fmt.Errorf("non-fast-forward merges are not yet implemented")
+	return threeWayMerge(repoRoot, db, currentBranch, branchToMerge, currentCommitHash, mergeCommitHash, author, committer)
 }
 
-func fastForwardMerge(repoRoot, currentBranch, branchToMerge, mergeCommitHash string) error {
-	branchPath := filepath.Join(repoRoot, ".mini-git", "refs", "heads", currentBranch)
-	if err := os.WriteFile(branchPath, []byte(mergeCommitHash), 0644); err != nil {
-		return fmt.Errorf("failed to update branch reference: %v", err)
+func threeWayMerge(repoRoot string, db *objects.Database, currentBranch, branchToMerge, currentCommitHash, mergeCommitHash string, author, committer identity.Identity) error {
+	bases, err := history.MergeBases(db, currentCommitHash, mergeCommitHash)
+	if err != nil {
+		return fmt.Errorf("failed to find merge base: %v", err)
+	}
+
+	baseFiles, err := mergeBaseFiles(db, bases)
+	if err != nil {
+		return fmt.Errorf("failed to read merge base tree: %v", err)
 	}
 
-	if err := checkoutCommit(repoRoot, mergeCommitHash); err != nil {
-		return fmt.Errorf("failed to update working directory: %v", err)
+	oursFiles, err := readCommitTree(db, currentCommitHash)
+	if err != nil {
+		return fmt.Errorf("failed to read current tree: %v", err)
 	}
 
-	fmt.Printf("Fast-forward merge successful. %s merged into %s.\n", branchToMerge, currentBranch)
+	theirsFiles, err := readCommitTree(db, mergeCommitHash)
+	if err != nil {
+		return fmt.Errorf("failed to read merge tree: %v", err)
+	}
+
+	merged, conflicts, err := mergeTrees(db, baseFiles, oursFiles, theirsFiles, currentBranch, branchToMerge)
+	if err != nil {
+		return err
+	}
+
+	// Refuse before touching anything if the merge would clobber local
+	// changes to the paths it writes.
+	idx, err := index.Read(repoRoot)
+	if err != nil {
+		return err
+	}
+	// The merge commit is built from the whole index, so staged changes
+	// would be committed along with it.
+	var staged []string
+	indexFiles := idx.Files()
+	for _, path := range sortedKeys(unionKeys(indexFiles, oursFiles)) {
+		if indexFiles[path] != oursFiles[path] {
+			staged = append(staged, path)
+		}
+	}
+	if len(staged) > 0 {
+		return fmt.Errorf("your index contains uncommitted changes:\n\t%s\ncommit or reset them before you merge", strings.Join(staged, "\n\t"))
+	}
+	result := make(map[string]string, len(merged)+len(conflicts))
+	for path, hash := range merged {
+		result[path] = hash
+	}
+	for path, conflict := range conflicts {
+		b, err := db.NewBlob(conflict.content)
+		if err != nil {
+			return err
+		}
+		result[path] = b.Hash
+	}
+	if err := checkOverwrites(repoRoot, db, idx, oursFiles, result, "merge"); err != nil {
+		return err
+	}
+
+	if err := writeMergeResult(repoRoot, db, idx, oursFiles, merged, conflicts); err != nil {
+		return fmt.Errorf("failed to write merge result: %v", err)
+	}
+
+	mergeHeadPath := filepath.Join(repoRoot, ".mini-git", "MERGE_HEAD")
+	if err := os.WriteFile(mergeHeadPath, []byte(mergeCommitHash+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write MERGE_HEAD: %v", err)
+	}
+
+	if len(conflicts) > 0 {
+		for _, path := range sortedKeys(conflicts) {
+			fmt.Printf("CONFLICT (%s): Merge conflict in %s\n", conflicts[path].kind, path)
+		}
+		return fmt.Errorf("automatic merge failed; fix conflicts and then commit the result")
+	}
+
+	message := fmt.Sprintf("Merge branch '%s' into %s", branchToMerge, currentBranch)
+	if err := (&Repo{Root: repoRoot, DB: db}).Commit(message, author, committer); err != nil {
+		return fmt.Errorf("failed to create merge commit: %v", err)
+	}
+
+	fmt.Printf("Merge made by the 'three-way' strategy. %s merged into %s.\n", branchToMerge, currentBranch)
 	return nil
 }
 
-func isAncestor(repoRoot, possibleAncestor, commit string) (bool, error) {
-	for commit != "" {
-		if commit == possibleAncestor {
-			return true, nil
+// mergeBaseFiles returns the file listing to use as the common ancestor. When
+// criss-cross history leaves several merge bases, they are merged with each
+// other first and the result, conflict markers included, acts as a virtual
+// base.
+func mergeBaseFiles(db *objects.Database, bases []string) (map[string]string, error) {
+	if len(bases) == 0 {
+		// Unrelated histories merge against an empty base.
+		return make(map[string]string), nil
+	}
+
+	files, err := readCommitTree(db, bases[0])
+	if err != nil {
+		return nil, err
+	}
+
+	for _, next := range bases[1:] {
+		innerBases, err := history.MergeBases(db, bases[0], next)
+		if err != nil {
+			return nil, err
+		}
+		innerFiles, err := mergeBaseFiles(db, innerBases)
+		if err != nil {
+			return nil, err
+		}
+		nextFiles, err := readCommitTree(db, next)
+		if err != nil {
+			return nil, err
 		}
 
-		commitObj, err := objects.RetrieveCommit(repoRoot, commit)
+		merged, conflicts, err := mergeTrees(db, innerFiles, files, nextFiles, "Temporary merge branch 1", "Temporary merge branch 2")
 		if err != nil {
-			return false, fmt.Errorf("failed to retrieve commit: %v", err)
+			return nil, err
 		}
+		for path, conflict := range conflicts {
+			b, err := db.NewBlob(conflict.content)
+			if err != nil {
+				return nil, err
+			}
+			hash, err := db.Put(b)
+			if err != nil {
+				return nil, err
+			}
+			merged[path] = hash
+		}
+		files = merged
+	}
+
+	return files, nil
+}
 
-		commit = commitObj.ParentHash
+type mergeConflict struct {
+	kind    string
+	content []byte
+}
+
+func mergeTrees(db *objects.Database, base, ours, theirs map[string]string, oursLabel, theirsLabel string) (map[string]string, map[string]mergeConflict, error) {
+	paths := make(map[string]bool)
+	for _, files := range []map[string]string{base, ours, theirs} {
+		for path := range files {
+			paths[path] = true
+		}
 	}
 
-	return false, nil
+	merged := make(map[string]string)
+	conflicts := make(map[string]mergeConflict)
+
+	for path := range paths {
+		baseHash, oursHash, theirsHash := base[path], ours[path], theirs[path]
+
+		switch {
+		case oursHash == theirsHash, baseHash == theirsHash:
+			if oursHash != "" {
+				merged[path] = oursHash
+			}
+			continue
+		case baseHash == oursHash:
+			if theirsHash != "" {
+				merged[path] = theirsHash
+			}
+			continue
+		}
+
+		if oursHash == "" || theirsHash == "" {
+			// One side deleted the file while the other modified it. Keep the
+			// surviving version in the working tree so it can be resolved.
+			survivor := oursHash
+			if survivor == "" {
+				survivor = theirsHash
+			}
+			b, err := db.Blob(survivor)
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to retrieve blob for %s: %v", path, err)
+			}
+			conflicts[path] = mergeConflict{kind: "modify/delete", content: b.Content}
+			continue
+		}
+
+		baseContent, err := blobContent(db, baseHash)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to retrieve base blob for %s: %v", path, err)
+		}
+		oursContent, err := blobContent(db, oursHash)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to retrieve blob for %s: %v", path, err)
+		}
+		theirsContent, err := blobContent(db, theirsHash)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to retrieve blob for %s: %v", path, err)
+		}
+
+		result := merge.ThreeWay(baseContent, oursContent, theirsContent, oursLabel, theirsLabel)
+		if result.Conflicts > 0 {
+			kind := "content"
+			if baseHash == "" {
+				kind = "add/add"
+			}
+			conflicts[path] = mergeConflict{kind: kind, content: result.Content}
+			continue
+		}
+
+		b, err := db.NewBlob(result.Content)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create blob for %s: %v", path, err)
+		}
+		hash, err := db.Put(b)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to store blob for %s: %v", path, err)
+		}
+		merged[path] = hash
+	}
+
+	return merged, conflicts, nil
 }
 
-func getCommitHash(repoRoot, branchName string) (string, error) {
-	branchPath := filepath.Join(repoRoot, ".mini-git", "refs", "heads", branchName)
-	commitHash, err := os.ReadFile(branchPath)
-	if err != nil {
-		return "", fmt.Errorf("failed to read branch file: %v", err)
+// writeMergeResult updates the work tree and idx from our tree to the merge
+// result, touching only the paths that differ. Conflicted paths keep our
+// version in the index until they are resolved and added again.
+func writeMergeResult(repoRoot string, db *objects.Database, idx *index.Index, oursFiles, merged map[string]string, conflicts map[string]mergeConflict) error {
+	for _, path := range sortedKeys(oursFiles) {
+		if _, kept := merged[path]; kept {
+			continue
+		}
+		if _, conflicted := conflicts[path]; conflicted {
+			continue
+		}
+		if err := removeWorkingFile(repoRoot, path); err != nil {
+			return err
+		}
+		idx.Remove(path)
+	}
+
+	for _, path := range sortedKeys(merged) {
+		hash := merged[path]
+		if oursFiles[path] == hash {
+			continue
+		}
+		b, err := db.Blob(hash)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve blob for %s: %v", path, err)
+		}
+		if err := writeWorkingFile(repoRoot, path, b.Content); err != nil {
+			return err
+		}
+
+		info, err := os.Stat(filepath.Join(repoRoot, filepath.FromSlash(path)))
+		if err != nil {
+			return err
+		}
+		idx.Add(index.NewEntry(path, hash, info))
+	}
+
+	for _, path := range sortedKeys(conflicts) {
+		if err := writeWorkingFile(repoRoot, path, conflicts[path].content); err != nil {
+			return err
+		}
 	}
-	return strings.TrimSpace(string(commitHash)), nil
+
+	return idx.Write(repoRoot)
 }
 
-func checkoutCommit(repoRoot, commitHash string) error {
-	commit, err := objects.RetrieveCommit(repoRoot, commitHash)
-	if err != nil {
-		return fmt.Errorf("failed to retrieve commit: %v", err)
+func writeWorkingFile(repoRoot, path string, content []byte) error {
+	filePath := filepath.Join(repoRoot, path)
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directories for %s: %v", path, err)
 	}
+	if err := os.WriteFile(filePath, content, 0644); err != nil {
+		return fmt.Errorf("failed to write file %s: %v", path, err)
+	}
+	return nil
+}
 
-	tree, err := objects.RetrieveTree(repoRoot, commit.TreeHash)
+func blobContent(db *objects.Database, hash string) ([]byte, error) {
+	if hash == "" {
+		return nil, nil
+	}
+	b, err := db.Blob(hash)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve tree: %v", err)
+		return nil, err
+	}
+	return b.Content, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
+	return keys
+}
 
-	if err := updateWorkingDirectory(repoRoot, tree); err != nil {
-		return fmt.Errorf("failed to update working directory: %v", err)
+func fastForwardMerge(repoRoot string, db *objects.Database, ref, currentBranch, branchToMerge, oldHash, mergeCommitHash string, committer identity.Identity) error {
+	if err := switchCommit(repoRoot, db, oldHash, mergeCommitHash, false); err != nil {
+		return err
 	}
 
+	if err := refs.Update(repoRoot, ref, mergeCommitHash, committer, fmt.Sprintf("merge %s: Fast-forward", branchToMerge)); err != nil {
+		return fmt.Errorf("failed to update branch reference: %v", err)
+	}
+
+	fmt.Printf("Fast-forward merge successful. %s merged into %s.\n", branchToMerge, currentBranch)
 	return nil
 }
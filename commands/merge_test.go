@@ -0,0 +1,83 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// divergedRepo returns a repository whose master and feat branches both
+// changed a different file since they forked, with master checked out.
+func divergedRepo(t *testing.T) string {
+	t.Helper()
+
+	repo := tempRepo(t)
+	commitFiles(t, repo, "base", map[string]string{"f": "a\nb\nc\n", "other": "x\n", "third": "y\n"})
+	if err := Branch(repo, []string{"feat"}, johnDoe); err != nil {
+		t.Fatalf("Failed to create branch: %v", err)
+	}
+	commitFiles(t, repo, "ours", map[string]string{"third": "ours\n"})
+	if err := Checkout(repo, []string{"feat"}, johnDoe); err != nil {
+		t.Fatalf("Failed to check out feat: %v", err)
+	}
+	commitFiles(t, repo, "theirs", map[string]string{"f": "a\nb\nc\ntheirs\n"})
+	if err := Checkout(repo, []string{"master"}, johnDoe); err != nil {
+		t.Fatalf("Failed to check out master: %v", err)
+	}
+	return repo
+}
+
+func TestMergeCombinesBranches(t *testing.T) {
+	repo := divergedRepo(t)
+
+	if err := Merge(repo, []string{"feat"}, johnDoe, johnDoe); err != nil {
+		t.Fatalf("Merge failed: %v", err)
+	}
+	if got := readFile(t, repo, "f"); got != "a\nb\nc\ntheirs\n" {
+		t.Errorf("Expected their change to f, got %q", got)
+	}
+	if got := readFile(t, repo, "third"); got != "ours\n" {
+		t.Errorf("Expected our change to third, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".mini-git", "MERGE_HEAD")); !os.IsNotExist(err) {
+		t.Errorf("Expected MERGE_HEAD to be removed by the merge commit")
+	}
+}
+
+func TestMergeRefusesStagedChanges(t *testing.T) {
+	repo := divergedRepo(t)
+	head := headHash(t, repo)
+
+	writeFile(t, repo, "other", "STAGED\n")
+	if err := Add(repo, []string{"other"}); err != nil {
+		t.Fatalf("Failed to add other: %v", err)
+	}
+
+	if err := Merge(repo, []string{"feat"}, johnDoe, johnDoe); err == nil {
+		t.Fatalf("Expected merging with staged changes to fail")
+	}
+	if headHash(t, repo) != head {
+		t.Errorf("Expected HEAD to stay put")
+	}
+	if _, err := os.Stat(filepath.Join(repo, ".mini-git", "MERGE_HEAD")); !os.IsNotExist(err) {
+		t.Errorf("Expected no MERGE_HEAD after a refused merge")
+	}
+	if got := readFile(t, repo, "f"); got != "a\nb\nc\n" {
+		t.Errorf("Expected f to be left alone, got %q", got)
+	}
+	if got := readFile(t, repo, "other"); got != "STAGED\n" {
+		t.Errorf("Expected the staged change to survive, got %q", got)
+	}
+}
+
+func TestMergeRefusesToOverwriteLocalChanges(t *testing.T) {
+	repo := divergedRepo(t)
+
+	writeFile(t, repo, "f", "local\n")
+	if err := Merge(repo, []string{"feat"}, johnDoe, johnDoe); err == nil {
+		t.Fatalf("Expected merging over a modified file to fail")
+	}
+	if got := readFile(t, repo, "f"); got != "local\n" {
+		t.Errorf("Expected the local change to survive, got %q", got)
+	}
+}
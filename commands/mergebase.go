@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/nexxeln/mini-git/history"
+	"github.com/nexxeln/mini-git/revision"
+)
+
+func MergeBase(startPath string, args []string) error {
+	r, err := OpenRepo(startPath)
+	if err != nil {
+		return err
+	}
+	return r.MergeBase(args)
+}
+
+func (r *Repo) MergeBase(args []string) error {
+	repoRoot, db := r.Root, r.DB
+
+	all := false
+	checkAncestor := false
+	var revisions []string
+	for _, arg := range args {
+		switch arg {
+		case "--all", "-a":
+			all = true
+		case "--is-ancestor":
+			checkAncestor = true
+		default:
+			revisions = append(revisions, arg)
+		}
+	}
+
+	if len(revisions) < 2 || (checkAncestor && len(revisions) != 2) {
+		return fmt.Errorf("usage: mini-git merge-base [--all] <commit> <commit>... | --is-ancestor <commit> <commit>")
+	}
+
+	hashes := make([]string, len(revisions))
+	for i, rev := range revisions {
+		var err error
+		hashes[i], err = revision.ResolveCommit(repoRoot, db, rev)
+		if err != nil {
+			return err
+		}
+	}
+
+	if checkAncestor {
+		isAncestor, err := history.IsAncestor(db, hashes[0], hashes[1])
+		if err != nil {
+			return fmt.Errorf("failed to check ancestry: %v", err)
+		}
+		if !isAncestor {
+			return fmt.Errorf("%s is not an ancestor of %s", revisions[0], revisions[1])
+		}
+		return nil
+	}
+
+	bases, err := history.MergeBases(db, hashes...)
+	if err != nil {
+		return fmt.Errorf("failed to compute merge base: %v", err)
+	}
+
+	if len(bases) == 0 {
+		return fmt.Errorf("no common ancestor found")
+	}
+
+	if !all {
+		bases = bases[:1]
+	}
+	for _, base := range bases {
+		fmt.Println(base)
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/nexxeln/mini-git/objects"
+	"github.com/nexxeln/mini-git/repository"
+)
+
+// Repo is a repository to run commands against: the root of its work tree
+// and the object database holding its objects, which may use any
+// ObjectStore. Each command is a method on Repo; the package-level function
+// of the same name opens the repository containing a path, with its objects
+// on disk, and calls it.
+type Repo struct {
+	Root string
+	DB   *objects.Database
+}
+
+// OpenRepo opens the repository containing startPath.
+func OpenRepo(startPath string) (*Repo, error) {
+	repoRoot, err := repository.FindRoot(startPath)
+	if err != nil {
+		return nil, fmt.Errorf("not a mini-git repository (or any of the parent directories): %v", err)
+	}
+
+	db, err := objects.Open(repoRoot)
+	if err != nil {
+		return nil, err
+	}
+	return &Repo{Root: repoRoot, DB: db}, nil
+}
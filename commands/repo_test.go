@@ -0,0 +1,48 @@
+package commands
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/nexxeln/mini-git/objects"
+	"github.com/nexxeln/mini-git/tree"
+)
+
+func TestRepoWithMemoryDatabase(t *testing.T) {
+	repo := tempRepo(t)
+	r := &Repo{Root: repo, DB: objects.NewMemoryDatabase(objects.FormatMiniGit, tree.EncodingText)}
+
+	writeFile(t, repo, "f", "one\n")
+	if err := r.Add(repo, []string{"f"}); err != nil {
+		t.Fatalf("Failed to add f: %v", err)
+	}
+	if err := r.Commit("one", johnDoe, johnDoe); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+	writeFile(t, repo, "f", "two\n")
+	if err := r.Add(repo, []string{"f"}); err != nil {
+		t.Fatalf("Failed to add f: %v", err)
+	}
+	if err := r.Commit("two", johnDoe, johnDoe); err != nil {
+		t.Fatalf("Failed to commit: %v", err)
+	}
+
+	loose, err := filepath.Glob(filepath.Join(repo, ".mini-git", "objects", "??", "*"))
+	if err != nil || len(loose) != 0 {
+		t.Errorf("Expected no objects on disk, got %v (%v)", loose, err)
+	}
+
+	if err := r.Reset(repo, []string{"--hard", "HEAD~1"}, johnDoe); err != nil {
+		t.Fatalf("Failed to reset: %v", err)
+	}
+	if got := readFile(t, repo, "f"); got != "one\n" {
+		t.Errorf("Expected f to be reset from the memory database, got %q", got)
+	}
+	if err := r.Status(); err != nil {
+		t.Errorf("Status failed: %v", err)
+	}
+
+	if err := r.Gc(nil); err == nil {
+		t.Errorf("Expected gc to refuse a memory database")
+	}
+}
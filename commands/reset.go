@@ -0,0 +1,311 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/nexxeln/mini-git/identity"
+	"github.com/nexxeln/mini-git/index"
+	"github.com/nexxeln/mini-git/objects"
+	"github.com/nexxeln/mini-git/refs"
+	"github.com/nexxeln/mini-git/revision"
+)
+
+const resetUsage = "usage: mini-git reset [--soft | --mixed | --hard] [<commit>] | reset [<commit>] [--] <pathspec>..."
+
+// Reset moves the current branch, or a detached HEAD, to a commit. --soft
+// only moves the ref, --mixed (the default) also rebuilds the index from the
+// commit's tree and --hard rewrites the tracked files in the work tree as
+// well. Given paths, it instead copies their entries from the commit (HEAD
+// by default) into the index, unstaging them.
+func Reset(startPath string, args []string, committer identity.Identity) error {
+	r, err := OpenRepo(startPath)
+	if err != nil {
+		return err
+	}
+	return r.Reset(startPath, args, committer)
+}
+
+func (r *Repo) Reset(startPath string, args []string, committer identity.Identity) error {
+	repoRoot, db := r.Root, r.DB
+
+	mode := ""
+	var operands []string
+	separator := -1
+	for _, arg := range args {
+		switch {
+		case separator != -1:
+			operands = append(operands, arg)
+		case arg == "--":
+			separator = len(operands)
+		case arg == "--soft" || arg == "--mixed" || arg == "--hard":
+			if mode != "" && mode != arg {
+				return fmt.Errorf(resetUsage)
+			}
+			mode = arg
+		case len(arg) > 1 && arg[0] == '-':
+			return fmt.Errorf(resetUsage)
+		default:
+			operands = append(operands, arg)
+		}
+	}
+
+	// Without --, the first operand is a commit if it resolves to one and
+	// the rest are paths.
+	target := "HEAD"
+	var paths []string
+	switch {
+	case separator > 1:
+		return fmt.Errorf(resetUsage)
+	case separator == 1:
+		target, paths = operands[0], operands[1:]
+	case separator == 0:
+		paths = operands
+	case len(operands) > 0:
+		if _, err := revision.ResolveCommit(repoRoot, db, operands[0]); err == nil {
+			target, paths = operands[0], operands[1:]
+		} else if len(operands) == 1 && !pathExists(startPath, operands[0]) {
+			return err
+		} else {
+			paths = operands
+		}
+	}
+
+	if len(paths) > 0 {
+		if mode == "--soft" || mode == "--hard" {
+			return fmt.Errorf("cannot do a %s reset with paths", mode[2:])
+		}
+		return resetPaths(repoRoot, startPath, db, target, paths)
+	}
+	if mode == "" {
+		mode = "--mixed"
+	}
+	return resetCommit(repoRoot, db, target, mode, committer)
+}
+
+func pathExists(startPath, path string) bool {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(startPath, path)
+	}
+	_, err := os.Lstat(path)
+	return err == nil
+}
+
+func resetCommit(repoRoot string, db *objects.Database, target, mode string, committer identity.Identity) error {
+	mergeHeadPath := filepath.Join(repoRoot, ".mini-git", "MERGE_HEAD")
+	_, err := os.Stat(mergeHeadPath)
+	merging := err == nil
+	if merging && mode == "--soft" {
+		return fmt.Errorf("cannot do a soft reset in the middle of a merge")
+	}
+
+	commitHash, err := revision.ResolveCommit(repoRoot, db, target)
+	if err != nil {
+		return err
+	}
+
+	headRef, oldHash, err := refs.Head(repoRoot)
+	if err != nil {
+		return err
+	}
+
+	files, err := readCommitTree(db, commitHash)
+	if err != nil {
+		return err
+	}
+
+	switch mode {
+	case "--mixed":
+		if err := writeIndex(repoRoot, files); err != nil {
+			return err
+		}
+	case "--hard":
+		if err := resetWorkingTree(repoRoot, db, oldHash, files); err != nil {
+			return err
+		}
+	}
+
+	ref := headRef
+	if ref == "" {
+		ref = "HEAD"
+	}
+	if err := refs.Update(repoRoot, ref, commitHash, committer, "reset: moving to "+target); err != nil {
+		return err
+	}
+
+	if merging {
+		if err := os.Remove(mergeHeadPath); err != nil {
+			return fmt.Errorf("failed to remove MERGE_HEAD: %v", err)
+		}
+	}
+
+	if mode == "--hard" {
+		c, err := db.Commit(commitHash)
+		if err != nil {
+			return err
+		}
+		subject, _, _ := strings.Cut(c.Message, "\n")
+		fmt.Printf("HEAD is now at %s %s\n", shortHash(commitHash), subject)
+		return nil
+	}
+	if mode == "--mixed" {
+		return printUnstaged(repoRoot, db, files)
+	}
+	return nil
+}
+
+// resetWorkingTree makes the tracked files in the work tree and the index
+// match files. Files tracked by the index or by the commit at oldHash that
+// are not in files are deleted; untracked files are left alone.
+func resetWorkingTree(repoRoot string, db *objects.Database, oldHash string, files map[string]string) error {
+	idx, err := index.Read(repoRoot)
+	if err != nil {
+		return err
+	}
+	tracked, err := readCommitTree(db, oldHash)
+	if err != nil {
+		return err
+	}
+	for _, entry := range idx.Entries {
+		tracked[entry.Path] = entry.Hash
+	}
+
+	for _, path := range sortedKeys(tracked) {
+		if _, kept := files[path]; kept {
+			continue
+		}
+		if err := removeWorkingFile(repoRoot, path); err != nil {
+			return err
+		}
+	}
+
+	newIdx := &index.Index{}
+	for _, path := range sortedKeys(files) {
+		b, err := db.Blob(files[path])
+		if err != nil {
+			return fmt.Errorf("failed to retrieve blob for %s: %v", path, err)
+		}
+		if err := writeWorkingFile(repoRoot, path, b.Content); err != nil {
+			return err
+		}
+
+		info, err := os.Stat(filepath.Join(repoRoot, filepath.FromSlash(path)))
+		if err != nil {
+			return err
+		}
+		newIdx.Add(index.NewEntry(path, files[path], info))
+	}
+	return newIdx.Write(repoRoot)
+}
+
+// removeWorkingFile deletes a file and any directories it leaves empty.
+func removeWorkingFile(repoRoot, path string) error {
+	filePath := filepath.Join(repoRoot, filepath.FromSlash(path))
+	if err := os.Remove(filePath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove %s: %v", path, err)
+	}
+
+	for dir := filepath.Dir(filePath); dir != repoRoot && len(dir) > len(repoRoot); dir = filepath.Dir(dir) {
+		if os.Remove(dir) != nil {
+			break
+		}
+	}
+	return nil
+}
+
+func resetPaths(repoRoot, startPath string, db *objects.Database, target string, paths []string) error {
+	// Unstaging in a repository without commits empties the entries.
+	files := make(map[string]string)
+	if target != "HEAD" || headHasCommit(repoRoot) {
+		commitHash, err := revision.ResolveCommit(repoRoot, db, target)
+		if err != nil {
+			return err
+		}
+		files, err = readCommitTree(db, commitHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	specs, err := parsePathspecs(repoRoot, startPath, paths)
+	if err != nil {
+		return err
+	}
+
+	idx, err := index.Read(repoRoot)
+	if err != nil {
+		return err
+	}
+
+	for _, spec := range specs {
+		matched := make(map[string]bool)
+		for path := range files {
+			if spec.matches(path) {
+				matched[path] = true
+			}
+		}
+		for _, entry := range idx.Entries {
+			if spec.matches(entry.Path) {
+				matched[entry.Path] = true
+			}
+		}
+		if len(matched) == 0 {
+			return fmt.Errorf("pathspec '%s' did not match any files", spec.arg)
+		}
+
+		for path := range matched {
+			hash, exists := files[path]
+			if !exists {
+				idx.Remove(path)
+				continue
+			}
+			if entry, found := idx.Entry(path); found && entry.Hash == hash {
+				continue
+			}
+			idx.Add(index.Entry{Path: path, Hash: hash, Mode: 0100644})
+		}
+	}
+
+	if err := idx.Write(repoRoot); err != nil {
+		return err
+	}
+	return printUnstaged(repoRoot, db, idx.Files())
+}
+
+func headHasCommit(repoRoot string) bool {
+	_, hash, err := refs.Head(repoRoot)
+	return err == nil && hash != ""
+}
+
+// printUnstaged lists the staged files whose work tree copy differs.
+func printUnstaged(repoRoot string, db *objects.Database, files map[string]string) error {
+	var lines []string
+	for _, path := range sortedKeys(files) {
+		content, err := os.ReadFile(filepath.Join(repoRoot, filepath.FromSlash(path)))
+		if os.IsNotExist(err) {
+			lines = append(lines, "D\t"+path)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", path, err)
+		}
+
+		b, err := db.NewBlob(content)
+		if err != nil {
+			return err
+		}
+		if b.Hash != files[path] {
+			lines = append(lines, "M\t"+path)
+		}
+	}
+
+	if len(lines) > 0 {
+		fmt.Println("Unstaged changes after reset:")
+		for _, line := range lines {
+			fmt.Println(line)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package commands
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// twoCommitRepo commits f as "one", then f as "two" along with a new file g.
+// It returns the repository, the first commit and the staged hashes of f in
+// each commit.
+func twoCommitRepo(t *testing.T) (string, string, string, string) {
+	t.Helper()
+
+	repo := tempRepo(t)
+	first := commitFiles(t, repo, "one", map[string]string{"f": "one\n"})
+	oneHash := stagedHash(t, repo, "f")
+	commitFiles(t, repo, "two", map[string]string{"f": "two\n", "g": "g\n"})
+	return repo, first, oneHash, stagedHash(t, repo, "f")
+}
+
+func TestResetSoft(t *testing.T) {
+	repo, first, _, twoHash := twoCommitRepo(t)
+
+	if err := Reset(repo, []string{"--soft", "HEAD~1"}, johnDoe); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if headHash(t, repo) != first {
+		t.Errorf("Expected master to move back to the first commit")
+	}
+	if stagedHash(t, repo, "f") != twoHash || stagedHash(t, repo, "g") == "" {
+		t.Errorf("Expected a soft reset to keep the index")
+	}
+	if got := readFile(t, repo, "f"); got != "two\n" {
+		t.Errorf("Expected a soft reset to keep the work tree, got %q", got)
+	}
+}
+
+func TestResetMixed(t *testing.T) {
+	repo, first, oneHash, _ := twoCommitRepo(t)
+
+	if err := Reset(repo, []string{"HEAD~1"}, johnDoe); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if headHash(t, repo) != first {
+		t.Errorf("Expected master to move back to the first commit")
+	}
+	if stagedHash(t, repo, "f") != oneHash || stagedHash(t, repo, "g") != "" {
+		t.Errorf("Expected the index to match the first commit")
+	}
+	if got := readFile(t, repo, "f"); got != "two\n" {
+		t.Errorf("Expected a mixed reset to keep the work tree, got %q", got)
+	}
+}
+
+func TestResetHard(t *testing.T) {
+	repo, first, oneHash, _ := twoCommitRepo(t)
+	writeFile(t, repo, "notes", "untracked\n")
+
+	if err := Reset(repo, []string{"--hard", first}, johnDoe); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if headHash(t, repo) != first {
+		t.Errorf("Expected master to move back to the first commit")
+	}
+	if stagedHash(t, repo, "f") != oneHash {
+		t.Errorf("Expected the index to match the first commit")
+	}
+	if got := readFile(t, repo, "f"); got != "one\n" {
+		t.Errorf("Expected f from the first commit, got %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(repo, "g")); !os.IsNotExist(err) {
+		t.Errorf("Expected g, which the first commit lacks, to be removed")
+	}
+	if got := readFile(t, repo, "notes"); got != "untracked\n" {
+		t.Errorf("Expected the untracked file to survive, got %q", got)
+	}
+}
+
+func TestResetPathsUnstages(t *testing.T) {
+	repo, _, _, twoHash := twoCommitRepo(t)
+	writeFile(t, repo, "f", "three\n")
+	writeFile(t, repo, "new", "new\n")
+	if err := Add(repo, []string{"f", "new"}); err != nil {
+		t.Fatalf("Failed to add files: %v", err)
+	}
+
+	if err := Reset(repo, []string{"f", "new"}, johnDoe); err != nil {
+		t.Fatalf("Reset failed: %v", err)
+	}
+	if stagedHash(t, repo, "f") != twoHash {
+		t.Errorf("Expected f to be unstaged back to HEAD")
+	}
+	if stagedHash(t, repo, "new") != "" {
+		t.Errorf("Expected new to be removed from the index")
+	}
+	if got := readFile(t, repo, "f"); got != "three\n" {
+		t.Errorf("Expected the work tree to keep the change, got %q", got)
+	}
+
+	if err := Reset(repo, []string{"--hard", "HEAD", "--", "f"}, johnDoe); err == nil {
+		t.Errorf("Expected a hard reset with paths to fail")
+	}
+}
@@ -0,0 +1,33 @@
+package commands
+
+import (
+	"fmt"
+
+	"github.com/nexxeln/mini-git/revision"
+)
+
+func RevParse(startPath string, args []string) error {
+	r, err := OpenRepo(startPath)
+	if err != nil {
+		return err
+	}
+	return r.RevParse(args)
+}
+
+func (r *Repo) RevParse(args []string) error {
+	repoRoot, db := r.Root, r.DB
+
+	if len(args) == 0 {
+		return fmt.Errorf("usage: mini-git rev-parse <revision>...")
+	}
+
+	for _, arg := range args {
+		hash, err := revision.Resolve(repoRoot, db, arg)
+		if err != nil {
+			return err
+		}
+		fmt.Println(hash)
+	}
+
+	return nil
+}
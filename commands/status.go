@@ -1,45 +1,73 @@
 package commands
 
 import (
-	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 
-	"github.com/nexxeln/mini-git/blob"
+	"github.com/nexxeln/mini-git/ignore"
+	"github.com/nexxeln/mini-git/index"
 	"github.com/nexxeln/mini-git/objects"
-	"github.com/nexxeln/mini-git/repository"
+	"github.com/nexxeln/mini-git/refs"
 )
 
 func Status(startPath string) error {
-	repoRoot, err := repository.FindRoot(startPath)
+	r, err := OpenRepo(startPath)
 	if err != nil {
-		return fmt.Errorf("not a mini-git repository (or any of the parent directories): %v", err)
+		return err
 	}
+	return r.Status()
+}
+
+func (r *Repo) Status() error {
+	repoRoot, db := r.Root, r.DB
 
 	branch, err := getCurrentBranch(repoRoot)
 	if err != nil {
 		return fmt.Errorf("failed to get current branch: %v", err)
 	}
 
-	fmt.Printf("On branch %s\n", branch)
+	if branch == "detached HEAD" {
+		_, hash, err := refs.Head(repoRoot)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("HEAD detached at %s\n", shortHash(hash))
+	} else {
+		fmt.Printf("On branch %s\n", branch)
+	}
 
-	latestCommitTree, err := getLatestCommitTree(repoRoot)
+	latestCommitTree, err := getLatestCommitTree(repoRoot, db)
 	if err != nil {
 		return fmt.Errorf("failed to get latest commit tree: %v", err)
 	}
 
-	staged, err := getStagedChanges(repoRoot, latestCommitTree)
+	idx, err := index.Read(repoRoot)
+	if err != nil {
+		return fmt.Errorf("failed to read index: %v", err)
+	}
+
+	staged := getStagedChanges(idx, latestCommitTree)
+
+	matcher, err := ignore.New(repoRoot)
 	if err != nil {
-		return fmt.Errorf("failed to get staged changes: %v", err)
+		return err
 	}
 
-	unstaged, err := getUnstagedChanges(repoRoot, staged, latestCommitTree)
+	unstaged, refreshed, err := getUnstagedChanges(repoRoot, db, idx, matcher, staged, latestCommitTree)
 	if err != nil {
 		return fmt.Errorf("failed to get unstaged changes: %v", err)
 	}
 
+	// Save the stat data of files that were hashed and found unchanged so
+	// the next status can skip them.
+	if refreshed {
+		if err := idx.Write(repoRoot); err != nil {
+			return err
+		}
+	}
+
 	if len(staged) > 0 {
 		fmt.Println("\nChanges to be committed:")
 		for _, file := range staged {
@@ -80,7 +108,7 @@ func getCurrentBranch(repoRoot string) (string, error) {
 	return "detached HEAD", nil
 }
 
-func getLatestCommitTree(repoRoot string) (map[string]string, error) {
+func getLatestCommitTree(repoRoot string, db *objects.Database) (map[string]string, error) {
 	headPath := filepath.Join(repoRoot, ".mini-git", "HEAD")
 	headContent, err := os.ReadFile(headPath)
 	if err != nil {
@@ -105,101 +133,115 @@ func getLatestCommitTree(repoRoot string) (map[string]string, error) {
 	}
 
 	// If commitHash is empty, it means there are no commits yet
-	if commitHash == "" {
-		return make(map[string]string), nil
-	}
-
-	commit, err := objects.RetrieveCommit(repoRoot, commitHash)
-	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve commit: %v", err)
-	}
-
-	tree, err := objects.RetrieveTree(repoRoot, commit.TreeHash)
-	if err != nil {
-		return nil, fmt.Errorf("failed to retrieve tree: %v", err)
-	}
-
-	treeFiles := make(map[string]string)
-	for _, entry := range tree.Entries {
-		treeFiles[entry.Name] = entry.Hash
-	}
-
-	return treeFiles, nil
+	return readCommitTree(db, commitHash)
 }
 
-func getStagedChanges(repoPath string, latestCommitTree map[string]string) ([]string, error) {
-	indexPath := filepath.Join(repoPath, ".mini-git", "index")
-	indexFile, err := os.Open(indexPath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			return []string{}, nil
-		}
-		return nil, fmt.Errorf("failed to open index file: %v", err)
-	}
-	defer indexFile.Close()
-
+func getStagedChanges(idx *index.Index, latestCommitTree map[string]string) []string {
 	var staged []string
-	scanner := bufio.NewScanner(indexFile)
-	for scanner.Scan() {
-		parts := strings.Fields(scanner.Text())
-		if len(parts) == 2 {
-			fileName := parts[1]
-			fileHash := parts[0]
-			if committedHash, exists := latestCommitTree[fileName]; !exists || committedHash != fileHash {
-				staged = append(staged, fileName)
-			}
+	for _, entry := range idx.Entries {
+		if committedHash, exists := latestCommitTree[entry.Path]; !exists || committedHash != entry.Hash {
+			staged = append(staged, entry.Path)
 		}
 	}
-
-	return staged, scanner.Err()
+	return staged
 }
 
-func getUnstagedChanges(repoRoot string, staged []string, committedFiles map[string]string) ([]string, error) {
+// getUnstagedChanges lists work tree files that differ from the last commit
+// and are not staged, leaving out ignored files that are not tracked. Files
+// whose stat data matches their index entry are taken to hold the staged
+// content without being read. It also reports whether any index entries had
+// their stat data refreshed.
+func getUnstagedChanges(repoRoot string, db *objects.Database, idx *index.Index, matcher *ignore.Matcher, staged []string, committedFiles map[string]string) ([]string, bool, error) {
 	stagedMap := make(map[string]bool)
 	for _, file := range staged {
 		stagedMap[file] = true
 	}
 
+	tracked := trackedDirs(committedFiles)
+
 	var unstaged []string
+	refreshed := false
 	err := filepath.Walk(repoRoot, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
-		if info.IsDir() {
-			if info.Name() == ".mini-git" {
-				return filepath.SkipDir
-			}
-			return nil
+		if info.IsDir() && info.Name() == ".mini-git" {
+			return filepath.SkipDir
 		}
 
 		relPath, err := filepath.Rel(repoRoot, path)
 		if err != nil {
 			return err
 		}
+		relPath = filepath.ToSlash(relPath)
+
+		if info.IsDir() {
+			// Ignored directories are only entered to look at tracked files.
+			if relPath == "." || tracked[relPath] {
+				return nil
+			}
+			ignored, err := matcher.Ignored(relPath, true)
+			if err != nil || !ignored {
+				return err
+			}
+			return filepath.SkipDir
+		}
+
+		if stagedMap[relPath] {
+			return nil
+		}
 
-		if !stagedMap[relPath] {
-			if storedHash, exists := committedFiles[relPath]; exists {
-				// File exists in the commit, check if it's modified
-				content, err := os.ReadFile(path)
-				if err != nil {
-					return err
-				}
-				newBlob, err := blob.NewBlob(content)
-				if err != nil {
-					return err
-				}
-				if newBlob.Hash != storedHash {
-					unstaged = append(unstaged, relPath)
-				}
-			} else {
+		storedHash, exists := committedFiles[relPath]
+		if !exists {
+			ignored, err := matcher.Ignored(relPath, false)
+			if err != nil {
+				return err
+			}
+			if !ignored {
 				// New file
 				unstaged = append(unstaged, relPath)
 			}
+			return nil
 		}
 
+		// File exists in the commit, check if it's modified
+		entry, indexed := idx.Entry(relPath)
+		if indexed && idx.Unchanged(entry, info) {
+			if entry.Hash != storedHash {
+				unstaged = append(unstaged, relPath)
+			}
+			return nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		newBlob, err := db.NewBlob(content)
+		if err != nil {
+			return err
+		}
+		if newBlob.Hash != storedHash {
+			unstaged = append(unstaged, relPath)
+		}
+		if indexed && newBlob.Hash == entry.Hash && idx.Refresh(relPath, info) {
+			refreshed = true
+		}
 		return nil
 	})
 
-	return unstaged, err
+	return unstaged, refreshed, err
+}
+
+// trackedDirs returns the set of directories that contain, at any depth, a
+// file in files.
+func trackedDirs(files map[string]string) map[string]bool {
+	dirs := make(map[string]bool)
+	for path := range files {
+		for dir := filepath.ToSlash(filepath.Dir(path)); dir != "." && !dirs[dir]; dir = filepath.ToSlash(filepath.Dir(dir)) {
+			dirs[dir] = true
+		}
+	}
+	return dirs
 }
@@ -0,0 +1,149 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nexxeln/mini-git/identity"
+	"github.com/nexxeln/mini-git/objects"
+	"github.com/nexxeln/mini-git/refs"
+	"github.com/nexxeln/mini-git/revision"
+	"github.com/nexxeln/mini-git/tag"
+)
+
+const tagUsage = "usage: mini-git tag [-l] | tag [-a] [-m <message>] <name> [<object>] | tag -d <name>..."
+
+// Tag lists, creates and deletes tags. tagger is only called to create an
+// annotated tag, so the other operations work without a valid identity.
+func Tag(startPath string, args []string, tagger func() (identity.Identity, error)) error {
+	r, err := OpenRepo(startPath)
+	if err != nil {
+		return err
+	}
+	return r.Tag(args, tagger)
+}
+
+func (r *Repo) Tag(args []string, tagger func() (identity.Identity, error)) error {
+	repoRoot, db := r.Root, r.DB
+
+	annotate := false
+	remove := false
+	message := ""
+	var names []string
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "-l" || arg == "--list":
+		case arg == "-a" || arg == "--annotate":
+			annotate = true
+		case arg == "-d" || arg == "--delete":
+			remove = true
+		case arg == "-m":
+			if i+1 == len(args) {
+				return fmt.Errorf(tagUsage)
+			}
+			i++
+			message = args[i]
+			annotate = true
+		case strings.HasPrefix(arg, "--message="):
+			message = strings.TrimPrefix(arg, "--message=")
+			annotate = true
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf(tagUsage)
+		default:
+			names = append(names, arg)
+		}
+	}
+
+	switch {
+	case remove:
+		if len(names) == 0 || annotate {
+			return fmt.Errorf(tagUsage)
+		}
+		return deleteTags(repoRoot, names)
+	case len(names) == 0:
+		if annotate {
+			return fmt.Errorf(tagUsage)
+		}
+		return listTags(repoRoot)
+	case len(names) > 2:
+		return fmt.Errorf(tagUsage)
+	}
+
+	if annotate && message == "" {
+		return fmt.Errorf("an annotated tag needs a message; use -m <message>")
+	}
+
+	target := "HEAD"
+	if len(names) == 2 {
+		target = names[1]
+	}
+	return createTag(repoRoot, db, names[0], target, annotate, message, tagger)
+}
+
+func listTags(repoRoot string) error {
+	tags, err := refs.List(repoRoot, "refs/tags/")
+	if err != nil {
+		return err
+	}
+
+	for _, ref := range tags {
+		fmt.Println(strings.TrimPrefix(ref, "refs/tags/"))
+	}
+	return nil
+}
+
+func createTag(repoRoot string, db *objects.Database, name, target string, annotate bool, message string, tagger func() (identity.Identity, error)) error {
+	if !refs.ValidName(name) {
+		return fmt.Errorf("'%s' is not a valid tag name", name)
+	}
+
+	ref := "refs/tags/" + name
+	if refs.Exists(repoRoot, ref) {
+		return fmt.Errorf("tag '%s' already exists", name)
+	}
+
+	hash, err := revision.Resolve(repoRoot, db, target)
+	if err != nil {
+		return err
+	}
+
+	if annotate {
+		kind, _, err := db.ReadRaw(hash)
+		if err != nil {
+			return err
+		}
+
+		id, err := tagger()
+		if err != nil {
+			return err
+		}
+		t := tag.NewTag(hash, string(kind), name, id.String(), message)
+		t.TaggerDate = id.When
+		hash, err = db.Put(t)
+		if err != nil {
+			return fmt.Errorf("failed to store tag: %v", err)
+		}
+	}
+
+	return refs.Write(repoRoot, ref, hash)
+}
+
+func deleteTags(repoRoot string, names []string) error {
+	for _, name := range names {
+		ref := "refs/tags/" + name
+		if !refs.ValidName(name) || !refs.Exists(repoRoot, ref) {
+			return fmt.Errorf("tag '%s' not found", name)
+		}
+
+		hash, err := refs.Read(repoRoot, ref)
+		if err != nil {
+			return err
+		}
+
+		if err := refs.Delete(repoRoot, ref); err != nil {
+			return err
+		}
+		fmt.Printf("Deleted tag '%s' (was %s)\n", name, shortHash(hash))
+	}
+	return nil
+}
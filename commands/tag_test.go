@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/nexxeln/mini-git/identity"
+	"github.com/nexxeln/mini-git/refs"
+)
+
+func TestTagOnlyNeedsTaggerToAnnotate(t *testing.T) {
+	repo := tempRepo(t)
+	head := commitFiles(t, repo, "one", map[string]string{"f": "one\n"})
+
+	badTagger := func() (identity.Identity, error) {
+		return identity.Identity{}, fmt.Errorf("invalid committer identity")
+	}
+	if err := Tag(repo, []string{"v1"}, badTagger); err != nil {
+		t.Fatalf("Failed to create a lightweight tag: %v", err)
+	}
+	if hash, err := refs.Read(repo, "refs/tags/v1"); err != nil || hash != head {
+		t.Errorf("Expected v1 to point at %s, got %s (%v)", head, hash, err)
+	}
+	if err := Tag(repo, []string{"-l"}, badTagger); err != nil {
+		t.Errorf("Failed to list tags: %v", err)
+	}
+	if err := Tag(repo, []string{"-a", "-m", "Release", "v2"}, badTagger); err == nil {
+		t.Errorf("Expected an annotated tag to need a valid tagger")
+	}
+	if err := Tag(repo, []string{"-d", "v1"}, badTagger); err != nil {
+		t.Errorf("Failed to delete a tag: %v", err)
+	}
+
+	goodTagger := func() (identity.Identity, error) { return johnDoe, nil }
+	if err := Tag(repo, []string{"-a", "-m", "Release", "v2"}, goodTagger); err != nil {
+		t.Fatalf("Failed to create an annotated tag: %v", err)
+	}
+	if hash, err := refs.Read(repo, "refs/tags/v2"); err != nil || hash == head {
+		t.Errorf("Expected v2 to point at a tag object, got %s (%v)", hash, err)
+	}
+}
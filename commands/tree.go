@@ -0,0 +1,86 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nexxeln/mini-git/objects"
+	"github.com/nexxeln/mini-git/tree"
+)
+
+// writeTree stores a tree object for every directory in files, which maps
+// slash-separated paths to blob hashes, and returns the root tree's hash.
+func writeTree(db *objects.Database, files map[string]string) (string, error) {
+	t := db.NewTree()
+	subdirs := make(map[string]map[string]string)
+
+	for path, hash := range files {
+		dir, rest, nested := strings.Cut(path, "/")
+		if !nested {
+			t.AddEntry(path, hash, tree.EntryTypeBlob)
+			continue
+		}
+		if subdirs[dir] == nil {
+			subdirs[dir] = make(map[string]string)
+		}
+		subdirs[dir][rest] = hash
+	}
+
+	for dir, subFiles := range subdirs {
+		subHash, err := writeTree(db, subFiles)
+		if err != nil {
+			return "", err
+		}
+		t.AddEntry(dir, subHash, tree.EntryTypeTree)
+	}
+
+	hash, err := db.Put(t)
+	if err != nil {
+		return "", fmt.Errorf("failed to store tree: %v", err)
+	}
+
+	return hash, nil
+}
+
+// readTree flattens the tree and all of its subtrees into a map of
+// slash-separated paths to blob hashes.
+func readTree(db *objects.Database, treeHash string) (map[string]string, error) {
+	files := make(map[string]string)
+	if err := collectTree(db, treeHash, "", files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+func collectTree(db *objects.Database, treeHash, prefix string, files map[string]string) error {
+	t, err := db.Tree(treeHash)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve tree: %v", err)
+	}
+
+	for _, entry := range t.Entries {
+		path := prefix + entry.Name
+		if entry.Type == tree.EntryTypeTree {
+			if err := collectTree(db, entry.Hash, path+"/", files); err != nil {
+				return err
+			}
+			continue
+		}
+		files[path] = entry.Hash
+	}
+
+	return nil
+}
+
+func readCommitTree(db *objects.Database, commitHash string) (map[string]string, error) {
+	if commitHash == "" {
+		return make(map[string]string), nil
+	}
+
+	commit, err := db.Commit(commitHash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve commit: %v", err)
+	}
+
+	return readTree(db, commit.TreeHash)
+}
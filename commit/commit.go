@@ -5,43 +5,53 @@ import (
 	"crypto/sha1"
 	"encoding/hex"
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
+
+	"github.com/nexxeln/mini-git/identity"
 )
 
 type Commit struct {
-	TreeHash   string
-	ParentHash string
-	Author     string
-	Committer  string
-	AuthorDate time.Time
-	CommitDate time.Time
-	Message    string
+	TreeHash     string
+	ParentHashes []string
+	Author       string
+	Committer    string
+	AuthorDate   time.Time
+	CommitDate   time.Time
+	Message      string
 }
 
-func NewCommit(treeHash, parentHash, author, committer, message string) *Commit {
+func NewCommit(treeHash string, parentHashes []string, author, committer, message string) *Commit {
 	now := time.Now()
 	return &Commit{
-		TreeHash:   treeHash,
-		ParentHash: parentHash,
-		Author:     author,
-		Committer:  committer,
-		AuthorDate: now,
-		CommitDate: now,
-		Message:    message,
+		TreeHash:     treeHash,
+		ParentHashes: parentHashes,
+		Author:       author,
+		Committer:    committer,
+		AuthorDate:   now,
+		CommitDate:   now,
+		Message:      message,
 	}
 }
 
+// FirstParent returns the commit's mainline parent, or an empty string for a
+// root commit.
+func (c *Commit) FirstParent() string {
+	if len(c.ParentHashes) == 0 {
+		return ""
+	}
+	return c.ParentHashes[0]
+}
+
 func (c *Commit) Serialize() ([]byte, error) {
 	var buffer bytes.Buffer
 
 	buffer.WriteString(fmt.Sprintf("tree %s\n", c.TreeHash))
-	if c.ParentHash != "" {
-		buffer.WriteString(fmt.Sprintf("parent %s\n", c.ParentHash))
+	for _, parentHash := range c.ParentHashes {
+		buffer.WriteString(fmt.Sprintf("parent %s\n", parentHash))
 	}
-	buffer.WriteString(fmt.Sprintf("author %s %d +0000\n", c.Author, c.AuthorDate.Unix()))
-	buffer.WriteString(fmt.Sprintf("committer %s %d +0000\n", c.Committer, c.CommitDate.Unix()))
+	buffer.WriteString(fmt.Sprintf("author %s %s\n", c.Author, identity.FormatTime(c.AuthorDate)))
+	buffer.WriteString(fmt.Sprintf("committer %s %s\n", c.Committer, identity.FormatTime(c.CommitDate)))
 	buffer.WriteString("\n")
 	buffer.WriteString(c.Message)
 
@@ -92,11 +102,11 @@ func Deserialize(data []byte) (*Commit, error) {
 		case "tree":
 			commit.TreeHash = value
 		case "parent":
-			commit.ParentHash = value
+			commit.ParentHashes = append(commit.ParentHashes, value)
 		case "author":
-			commit.Author, commit.AuthorDate = parseAuthorLine(value)
+			commit.Author, commit.AuthorDate = identity.ParseSignature(value)
 		case "committer":
-			commit.Committer, commit.CommitDate = parseAuthorLine(value)
+			commit.Committer, commit.CommitDate = identity.ParseSignature(value)
 		default:
 			return nil, fmt.Errorf("unknown commit field: %s", key)
 		}
@@ -107,20 +117,6 @@ func Deserialize(data []byte) (*Commit, error) {
 	return commit, nil
 }
 
-func parseAuthorLine(line string) (string, time.Time) {
-	parts := strings.Split(line, " ")
-	if len(parts) < 3 {
-		return line, time.Time{}
-	}
-
-	timestamp, err := strconv.ParseInt(parts[len(parts)-2], 10, 64)
-	if err != nil {
-		return line, time.Time{}
-	}
-
-	return strings.Join(parts[:len(parts)-2], " "), time.Unix(timestamp, 0).UTC()
-}
-
 func (c *Commit) Hash() string {
 	serialized, _ := c.Serialize()
 	hash := sha1.Sum(serialized)
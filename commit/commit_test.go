@@ -2,6 +2,7 @@ package commit
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 )
@@ -13,13 +14,13 @@ func TestNewCommit(t *testing.T) {
 	committer := "Jane Doe <jane@example.com>"
 	message := "Initial commit"
 
-	commit := NewCommit(treeHash, parentHash, author, committer, message)
+	commit := NewCommit(treeHash, []string{parentHash}, author, committer, message)
 
 	if commit.TreeHash != treeHash {
 		t.Errorf("Expected tree hash %s, got %s", treeHash, commit.TreeHash)
 	}
-	if commit.ParentHash != parentHash {
-		t.Errorf("Expected parent hash %s, got %s", parentHash, commit.ParentHash)
+	if len(commit.ParentHashes) != 1 || commit.ParentHashes[0] != parentHash {
+		t.Errorf("Expected parent hashes [%s], got %v", parentHash, commit.ParentHashes)
 	}
 	if commit.Author != author {
 		t.Errorf("Expected author %s, got %s", author, commit.Author)
@@ -40,7 +41,7 @@ func TestCommitSerialize(t *testing.T) {
 	message := "Initial commit"
 	timestamp := time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
 
-	commit := NewCommit(treeHash, parentHash, author, committer, message)
+	commit := NewCommit(treeHash, []string{parentHash}, author, committer, message)
 	commit.AuthorDate = timestamp
 	commit.CommitDate = timestamp
 
@@ -77,8 +78,8 @@ Initial commit`
 	if commit.TreeHash != "0123456789abcdef0123456789abcdef01234567" {
 		t.Errorf("Incorrect tree hash")
 	}
-	if commit.ParentHash != "fedcba9876543210fedcba9876543210fedcba98" {
-		t.Errorf("Incorrect parent hash")
+	if len(commit.ParentHashes) != 1 || commit.ParentHashes[0] != "fedcba9876543210fedcba9876543210fedcba98" {
+		t.Errorf("Incorrect parent hashes")
 	}
 	if commit.Author != "John Doe <john@example.com>" {
 		t.Errorf("Incorrect author")
@@ -97,3 +98,101 @@ Initial commit`
 		t.Errorf("Incorrect commit date")
 	}
 }
+
+func TestCommitMultipleParentsRoundTrip(t *testing.T) {
+	parents := []string{
+		"fedcba9876543210fedcba9876543210fedcba98",
+		"00112233445566778899aabbccddeeff00112233",
+	}
+	original := NewCommit("0123456789abcdef0123456789abcdef01234567", parents, "John Doe <john@example.com>", "John Doe <john@example.com>", "Merge branch 'feature'")
+	original.AuthorDate = time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+	original.CommitDate = original.AuthorDate
+
+	serialized, err := original.Serialize()
+	if err != nil {
+		t.Fatalf("Failed to serialize commit: %v", err)
+	}
+
+	deserialized, err := Deserialize(serialized)
+	if err != nil {
+		t.Fatalf("Failed to deserialize commit: %v", err)
+	}
+
+	if len(deserialized.ParentHashes) != len(parents) {
+		t.Fatalf("Expected %d parents, got %d", len(parents), len(deserialized.ParentHashes))
+	}
+	for i, parent := range parents {
+		if deserialized.ParentHashes[i] != parent {
+			t.Errorf("Parent %d: expected %s, got %s", i, parent, deserialized.ParentHashes[i])
+		}
+	}
+
+	if deserialized.Hash() != original.Hash() {
+		t.Errorf("Round-tripped commit hash changed. Expected %s, got %s", original.Hash(), deserialized.Hash())
+	}
+}
+
+func TestRootCommitHasNoParentLine(t *testing.T) {
+	commit := NewCommit("0123456789abcdef0123456789abcdef01234567", nil, "John Doe <john@example.com>", "John Doe <john@example.com>", "Initial commit")
+
+	serialized, _ := commit.Serialize()
+	if strings.Contains(string(serialized), "parent ") {
+		t.Errorf("Root commit should not contain a parent line, got %q", string(serialized))
+	}
+	if commit.FirstParent() != "" {
+		t.Errorf("Root commit should have no first parent, got %s", commit.FirstParent())
+	}
+}
+
+func TestCommitTimezoneRoundTrip(t *testing.T) {
+	commitData := "commit 200\x00" + `tree 0123456789abcdef0123456789abcdef01234567
+author John Doe <john@example.com> 1625140800 +0200
+committer Jane Doe <jane@example.com> 1625160600 -0530
+
+Initial commit`
+
+	commit, err := Deserialize([]byte(commitData))
+	if err != nil {
+		t.Fatalf("Failed to deserialize commit: %v", err)
+	}
+
+	if _, offset := commit.AuthorDate.Zone(); offset != 2*60*60 {
+		t.Errorf("Expected author offset +0200, got %d seconds", offset)
+	}
+	if _, offset := commit.CommitDate.Zone(); offset != -(5*60*60 + 30*60) {
+		t.Errorf("Expected committer offset -0530, got %d seconds", offset)
+	}
+	if hour := commit.AuthorDate.Hour(); hour != 14 {
+		t.Errorf("Expected the author date in local time 14:00, got hour %d", hour)
+	}
+
+	serialized, err := commit.Serialize()
+	if err != nil {
+		t.Fatalf("Failed to serialize commit: %v", err)
+	}
+	if !strings.Contains(string(serialized), "1625140800 +0200\n") || !strings.Contains(string(serialized), "1625160600 -0530\n") {
+		t.Errorf("Offsets did not survive a round trip:\n%s", serialized)
+	}
+}
+
+func TestCommitUTCHashUnchanged(t *testing.T) {
+	commitData := "commit 216\x00" + `tree 0123456789abcdef0123456789abcdef01234567
+parent fedcba9876543210fedcba9876543210fedcba98
+author John Doe <john@example.com> 1625097600 +0000
+committer Jane Doe <jane@example.com> 1625097600 +0000
+
+Initial commit`
+
+	commit, err := Deserialize([]byte(commitData))
+	if err != nil {
+		t.Fatalf("Failed to deserialize commit: %v", err)
+	}
+
+	serialized, err := commit.Serialize()
+	if err != nil {
+		t.Fatalf("Failed to serialize commit: %v", err)
+	}
+	if string(serialized) != commitData {
+		t.Errorf("Re-serializing a +0000 commit changed it:\n%q", serialized)
+	}
+}
@@ -0,0 +1,297 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Config holds the variables of one or more INI-style config files, in the
+// order they were read. Variables are named "section.key" or
+// "section.subsection.key"; the section and key are case-insensitive, the
+// subsection is not. A key may be assigned more than once, in which case Get
+// returns the last value and GetAll all of them.
+type Config struct {
+	entries []Entry
+}
+
+// Entry is one assignment, with its name in canonical form.
+type Entry struct {
+	Name  string
+	Value string
+}
+
+func New() *Config {
+	return &Config{}
+}
+
+// maxIncludeDepth limits how deeply files may include each other, which
+// also stops include cycles.
+const maxIncludeDepth = 10
+
+// Load reads the config file at path. A missing file yields an empty config.
+// The files named by include.path variables are read in their place;
+// relative paths are relative to the including file.
+func Load(path string) (*Config, error) {
+	c := New()
+	if err := c.load(path, 0); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) load(path string, depth int) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+
+	doc, err := parseDocument(data)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	for _, v := range doc.vars {
+		c.entries = append(c.entries, Entry{Name: v.name, Value: v.value})
+		if v.name != "include.path" {
+			continue
+		}
+		if depth == maxIncludeDepth {
+			return fmt.Errorf("%s: includes nested too deeply", path)
+		}
+
+		include, err := ExpandPath(v.value)
+		if err != nil {
+			return err
+		}
+		if !filepath.IsAbs(include) {
+			include = filepath.Join(filepath.Dir(path), include)
+		}
+		if err := c.load(include, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Parse parses the contents of a config file. Includes are not followed.
+func Parse(data []byte) (*Config, error) {
+	doc, err := parseDocument(data)
+	if err != nil {
+		return nil, err
+	}
+
+	c := New()
+	for _, v := range doc.vars {
+		c.entries = append(c.entries, Entry{Name: v.name, Value: v.value})
+	}
+	return c, nil
+}
+
+// Get returns the last value of a "section.key" name.
+func (c *Config) Get(name string) (string, bool) {
+	values := c.GetAll(name)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// GetAll returns every value of a name, in order.
+func (c *Config) GetAll(name string) []string {
+	name, err := canonicalName(name)
+	if err != nil {
+		return nil
+	}
+
+	var values []string
+	for _, entry := range c.entries {
+		if entry.Name == name {
+			values = append(values, entry.Value)
+		}
+	}
+	return values
+}
+
+// Entries returns all assignments in the order they were read.
+func (c *Config) Entries() []Entry {
+	return c.entries
+}
+
+// Bool returns the value of name as a boolean, and whether it is set.
+func (c *Config) Bool(name string) (bool, bool, error) {
+	value, exists := c.Get(name)
+	if !exists {
+		return false, false, nil
+	}
+	b, err := ParseBool(value)
+	if err != nil {
+		return false, true, fmt.Errorf("bad boolean config value '%s' for '%s'", value, name)
+	}
+	return b, true, nil
+}
+
+// Int returns the value of name as an integer, and whether it is set.
+func (c *Config) Int(name string) (int64, bool, error) {
+	value, exists := c.Get(name)
+	if !exists {
+		return 0, false, nil
+	}
+	n, err := ParseInt(value)
+	if err != nil {
+		return 0, true, fmt.Errorf("bad numeric config value '%s' for '%s'", value, name)
+	}
+	return n, true, nil
+}
+
+// Path returns the value of name with a leading ~ expanded, and whether it
+// is set.
+func (c *Config) Path(name string) (string, bool, error) {
+	value, exists := c.Get(name)
+	if !exists {
+		return "", false, nil
+	}
+	path, err := ExpandPath(value)
+	return path, true, err
+}
+
+// ParseBool parses true, yes and on, or false, no, off and the empty string,
+// in any case. Integers are true unless zero.
+func ParseBool(value string) (bool, error) {
+	switch strings.ToLower(value) {
+	case "true", "yes", "on":
+		return true, nil
+	case "false", "no", "off", "":
+		return false, nil
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n != 0, nil
+	}
+	return false, fmt.Errorf("invalid boolean %q", value)
+}
+
+// ParseInt parses a decimal integer, optionally followed by k, m or g to
+// multiply it by 1024, 1024² or 1024³.
+func ParseInt(value string) (int64, error) {
+	multiplier := int64(1)
+	if value != "" {
+		switch value[len(value)-1] {
+		case 'k', 'K':
+			multiplier = 1 << 10
+		case 'm', 'M':
+			multiplier = 1 << 20
+		case 'g', 'G':
+			multiplier = 1 << 30
+		}
+		if multiplier != 1 {
+			value = value[:len(value)-1]
+		}
+	}
+
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %q", value)
+	}
+	if n > 0 && n > (1<<63-1)/multiplier || n < 0 && n < -(1<<63)/multiplier {
+		return 0, fmt.Errorf("integer %q out of range", value)
+	}
+	return n * multiplier, nil
+}
+
+// ExpandPath replaces a leading ~ with the user's home directory.
+func ExpandPath(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to expand %s: %v", path, err)
+	}
+	return filepath.Join(home, path[1:]), nil
+}
+
+// Scope is one of the config files consulted by LoadAll.
+type Scope int
+
+const (
+	// System is shared by every user: /etc/mini-gitconfig, or the file named
+	// by MINI_GIT_CONFIG_SYSTEM. Setting MINI_GIT_CONFIG_NOSYSTEM skips it.
+	System Scope = iota
+	// Global belongs to the current user: ~/.mini-gitconfig, or the file
+	// named by MINI_GIT_CONFIG_GLOBAL.
+	Global
+	// Local belongs to a repository: .mini-git/config.
+	Local
+)
+
+func (s Scope) String() string {
+	switch s {
+	case System:
+		return "system"
+	case Global:
+		return "global"
+	case Local:
+		return "local"
+	}
+	return fmt.Sprintf("Scope(%d)", int(s))
+}
+
+// Path returns the config file for the scope. repoPath is only used by the
+// local scope, which needs a repository.
+func (s Scope) Path(repoPath string) (string, error) {
+	switch s {
+	case System:
+		if path := os.Getenv("MINI_GIT_CONFIG_SYSTEM"); path != "" {
+			return path, nil
+		}
+		return "/etc/mini-gitconfig", nil
+	case Global:
+		if path := os.Getenv("MINI_GIT_CONFIG_GLOBAL"); path != "" {
+			return path, nil
+		}
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to find home directory: %v", err)
+		}
+		return filepath.Join(home, ".mini-gitconfig"), nil
+	case Local:
+		if repoPath == "" {
+			return "", fmt.Errorf("not a mini-git repository")
+		}
+		return filepath.Join(repoPath, ".mini-git", "config"), nil
+	}
+	return "", fmt.Errorf("unknown config scope %d", int(s))
+}
+
+// LoadAll reads the system, global and local config files in that order, so
+// later scopes take precedence. Without a repoPath only the first two are
+// read, and a missing home directory skips the global file.
+func LoadAll(repoPath string) (*Config, error) {
+	scopes := []Scope{Global}
+	if os.Getenv("MINI_GIT_CONFIG_NOSYSTEM") == "" {
+		scopes = []Scope{System, Global}
+	}
+	if repoPath != "" {
+		scopes = append(scopes, Local)
+	}
+
+	c := New()
+	for _, scope := range scopes {
+		path, err := scope.Path(repoPath)
+		if err != nil {
+			if scope == Global {
+				continue
+			}
+			return nil, err
+		}
+		if err := c.load(path, 0); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
@@ -0,0 +1,223 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	data := []byte(`# a comment
+[core]
+	repositoryformatversion = 0
+	ObjectFormat = git
+	bare
+
+; another comment
+[user]
+	name = John Doe
+`)
+
+	c, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	tests := map[string]string{
+		"core.repositoryformatversion": "0",
+		"core.objectformat":            "git",
+		"CORE.OBJECTFORMAT":            "git",
+		"core.bare":                    "true",
+		"user.name":                    "John Doe",
+	}
+	for name, expected := range tests {
+		value, exists := c.Get(name)
+		if !exists || value != expected {
+			t.Errorf("Get(%q) = %q, %v; expected %q", name, value, exists, expected)
+		}
+	}
+
+	if _, exists := c.Get("user.email"); exists {
+		t.Errorf("Expected user.email to be unset")
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	if _, err := Parse([]byte("key = value\n")); err == nil {
+		t.Errorf("Expected an error for a key outside of a section")
+	}
+
+	if _, err := Parse([]byte("[core\n")); err == nil {
+		t.Errorf("Expected an error for an unterminated section header")
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mini-git-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	c, err := Load(filepath.Join(tempDir, "config"))
+	if err != nil {
+		t.Fatalf("Failed to load missing config: %v", err)
+	}
+
+	if _, exists := c.Get("core.bare"); exists {
+		t.Errorf("Expected an empty config")
+	}
+}
+
+func TestParseSubsectionsAndValues(t *testing.T) {
+	data := []byte(`[remote "Origin"]
+	url = https://example.com/repo  # trailing comment
+	fetch = one
+	fetch = two
+[Branch.Main]
+	remote = origin
+[alias]
+	quoted = "  keep ; this  " ; comment
+	escaped = a\tb\\c \"d\"
+	continued = first \
+second
+`)
+
+	c, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	tests := map[string]string{
+		"remote.Origin.url":   "https://example.com/repo",
+		"REMOTE.Origin.URL":   "https://example.com/repo",
+		"remote.Origin.fetch": "two",
+		"branch.main.remote":  "origin",
+		"alias.quoted":        "  keep ; this  ",
+		"alias.escaped":       "a\tb\\c \"d\"",
+		"alias.continued":     "first second",
+	}
+	for name, expected := range tests {
+		value, exists := c.Get(name)
+		if !exists || value != expected {
+			t.Errorf("Get(%q) = %q, %v; expected %q", name, value, exists, expected)
+		}
+	}
+
+	if _, exists := c.Get("remote.origin.url"); exists {
+		t.Errorf("Expected subsections to be case-sensitive")
+	}
+
+	fetch := c.GetAll("remote.Origin.fetch")
+	if len(fetch) != 2 || fetch[0] != "one" || fetch[1] != "two" {
+		t.Errorf("GetAll returned %q; expected [one two]", fetch)
+	}
+}
+
+func TestTypedGetters(t *testing.T) {
+	c, err := Parse([]byte("[core]\n\tbare\n\tfilemode = Off\n\tcount = 3\n\tbig = 2k\n\tbad = maybe\n\tdir = ~/work\n"))
+	if err != nil {
+		t.Fatalf("Failed to parse config: %v", err)
+	}
+
+	for name, expected := range map[string]bool{"core.bare": true, "core.filemode": false, "core.count": true} {
+		value, exists, err := c.Bool(name)
+		if err != nil || !exists || value != expected {
+			t.Errorf("Bool(%q) = %v, %v, %v; expected %v", name, value, exists, err, expected)
+		}
+	}
+	if _, _, err := c.Bool("core.bad"); err == nil {
+		t.Errorf("Expected an error for a non-boolean value")
+	}
+	if _, exists, err := c.Bool("core.missing"); exists || err != nil {
+		t.Errorf("Expected a missing boolean to be unset")
+	}
+
+	for name, expected := range map[string]int64{"core.count": 3, "core.big": 2048} {
+		value, exists, err := c.Int(name)
+		if err != nil || !exists || value != expected {
+			t.Errorf("Int(%q) = %d, %v, %v; expected %d", name, value, exists, err, expected)
+		}
+	}
+	if _, _, err := c.Int("core.bad"); err == nil {
+		t.Errorf("Expected an error for a non-numeric value")
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Skip("no home directory")
+	}
+	if path, _, err := c.Path("core.dir"); err != nil || path != filepath.Join(home, "work") {
+		t.Errorf("Path(core.dir) = %q, %v; expected %q", path, err, filepath.Join(home, "work"))
+	}
+}
+
+func TestLoadIncludes(t *testing.T) {
+	dir := t.TempDir()
+	writeConfig(t, filepath.Join(dir, "config"), "[user]\n\tname = Before\n[include]\n\tpath = extra\n[core]\n\tbare = false\n")
+	writeConfig(t, filepath.Join(dir, "extra"), "[user]\n\tname = Included\n\temail = inc@example.com\n[include]\n\tpath = config\n")
+
+	if _, err := Load(filepath.Join(dir, "config")); err == nil {
+		t.Fatalf("Expected an error for an include cycle")
+	}
+
+	writeConfig(t, filepath.Join(dir, "extra"), "[user]\n\tname = Included\n\temail = inc@example.com\n[include]\n\tpath = missing\n")
+	c, err := Load(filepath.Join(dir, "config"))
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if name, _ := c.Get("user.name"); name != "Included" {
+		t.Errorf("Expected the included name to override the earlier one, got %q", name)
+	}
+	if email, _ := c.Get("user.email"); email != "inc@example.com" {
+		t.Errorf("Expected the included email, got %q", email)
+	}
+}
+
+func TestLoadAll(t *testing.T) {
+	dir := t.TempDir()
+	repo := filepath.Join(dir, "repo")
+	if err := os.MkdirAll(filepath.Join(repo, ".mini-git"), 0755); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	t.Setenv("MINI_GIT_CONFIG_NOSYSTEM", "")
+	t.Setenv("MINI_GIT_CONFIG_SYSTEM", filepath.Join(dir, "system"))
+	t.Setenv("MINI_GIT_CONFIG_GLOBAL", filepath.Join(dir, "global"))
+
+	writeConfig(t, filepath.Join(dir, "system"), "[a]\n\tx = system\n\ty = system\n\tz = system\n")
+	writeConfig(t, filepath.Join(dir, "global"), "[a]\n\ty = global\n\tz = global\n")
+	writeConfig(t, filepath.Join(repo, ".mini-git", "config"), "[a]\n\tz = local\n")
+
+	c, err := LoadAll(repo)
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	for name, expected := range map[string]string{"a.x": "system", "a.y": "global", "a.z": "local"} {
+		if value, _ := c.Get(name); value != expected {
+			t.Errorf("Get(%q) = %q; expected %q", name, value, expected)
+		}
+	}
+
+	c, err = LoadAll("")
+	if err != nil {
+		t.Fatalf("LoadAll without a repository failed: %v", err)
+	}
+	if value, _ := c.Get("a.z"); value != "global" {
+		t.Errorf("Expected only system and global config outside a repository, got %q", value)
+	}
+
+	t.Setenv("MINI_GIT_CONFIG_NOSYSTEM", "1")
+	c, err = LoadAll("")
+	if err != nil {
+		t.Fatalf("LoadAll failed: %v", err)
+	}
+	if _, exists := c.Get("a.x"); exists {
+		t.Errorf("Expected MINI_GIT_CONFIG_NOSYSTEM to skip the system config")
+	}
+}
+
+func writeConfig(t *testing.T, path, content string) {
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
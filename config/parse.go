@@ -0,0 +1,280 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// document is a parsed config file that remembers which lines each section
+// header and variable came from, so it can be edited without disturbing
+// comments and formatting.
+type document struct {
+	lines    []string
+	sections []header
+	vars     []variable
+}
+
+// header is a section header. name is the canonical section name: the
+// lowercased section, followed by a dot and the subsection if there is one.
+type header struct {
+	name string
+	line int
+}
+
+// variable is one assignment. first and last are the indexes of the lines
+// it spans, which differ when the value is continued with a backslash.
+type variable struct {
+	name        string
+	value       string
+	first, last int
+}
+
+func parseDocument(data []byte) (*document, error) {
+	doc := &document{}
+	text := strings.TrimSuffix(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	if text != "" {
+		doc.lines = strings.Split(text, "\n")
+	}
+
+	section := ""
+	for i := 0; i < len(doc.lines); i++ {
+		line := strings.TrimSpace(doc.lines[i])
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+
+		if line[0] == '[' {
+			name, err := parseHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("invalid section header on line %d: %s", i+1, line)
+			}
+			section = name
+			doc.sections = append(doc.sections, header{name: name, line: i})
+			continue
+		}
+
+		if section == "" {
+			return nil, fmt.Errorf("key outside of a section on line %d: %s", i+1, line)
+		}
+
+		end := 0
+		for end < len(line) && isKeyChar(line[end]) {
+			end++
+		}
+		key, rest := line[:end], strings.TrimLeft(line[end:], " \t")
+		if !validKey(key) {
+			return nil, fmt.Errorf("invalid key on line %d: %s", i+1, line)
+		}
+
+		v := variable{name: section + "." + strings.ToLower(key), first: i}
+		switch {
+		case rest == "" || rest[0] == '#' || rest[0] == ';':
+			// A bare key is a boolean set to true.
+			v.value = "true"
+		case rest[0] == '=':
+			value, last, err := parseValue(doc.lines, i, rest[1:])
+			if err != nil {
+				return nil, fmt.Errorf("bad value on line %d: %v", i+1, err)
+			}
+			v.value = value
+			i = last
+		default:
+			return nil, fmt.Errorf("expected '=' after key on line %d: %s", i+1, line)
+		}
+		v.last = i
+		doc.vars = append(doc.vars, v)
+	}
+	return doc, nil
+}
+
+// parseHeader parses "[section]", "[section.subsection]" or
+// `[section "subsection"]` and returns the canonical section name. The
+// subsection keeps its case only in the quoted form.
+func parseHeader(line string) (string, error) {
+	i := 1
+	for i < len(line) && (isKeyChar(line[i]) || line[i] == '.') {
+		i++
+	}
+	name := strings.ToLower(line[1:i])
+	if name == "" || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") {
+		return "", fmt.Errorf("invalid section name")
+	}
+
+	if i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+		for i < len(line) && (line[i] == ' ' || line[i] == '\t') {
+			i++
+		}
+		if i == len(line) || line[i] != '"' || strings.Contains(name, ".") {
+			return "", fmt.Errorf("invalid subsection")
+		}
+
+		var subsection strings.Builder
+		for i++; i < len(line) && line[i] != '"'; i++ {
+			if line[i] == '\\' && i+1 < len(line) {
+				i++
+			}
+			subsection.WriteByte(line[i])
+		}
+		if i == len(line) {
+			return "", fmt.Errorf("unterminated subsection")
+		}
+		name += "." + subsection.String()
+		i++
+	}
+
+	if i == len(line) || line[i] != ']' {
+		return "", fmt.Errorf("missing ]")
+	}
+	if rest := strings.TrimSpace(line[i+1:]); rest != "" && rest[0] != '#' && rest[0] != ';' {
+		return "", fmt.Errorf("unexpected text after ]")
+	}
+	return name, nil
+}
+
+// parseValue parses the value s that starts on line i. Double quotes
+// preserve whitespace and comment characters, backslash escapes \", \\, \n,
+// \t and \b, and a backslash at the end of a line continues the value on the
+// next one. Unquoted leading and trailing whitespace is dropped. It returns
+// the value and the index of its last line.
+func parseValue(lines []string, i int, s string) (string, int, error) {
+	var value, space strings.Builder
+	started, quoted := false, false
+
+	write := func(c byte) {
+		value.WriteString(space.String())
+		space.Reset()
+		value.WriteByte(c)
+		started = true
+	}
+
+scan:
+	for j := 0; j < len(s); j++ {
+		switch c := s[j]; {
+		case c == '\\' && j+1 == len(s):
+			if i+1 == len(lines) {
+				return "", i, fmt.Errorf("backslash at end of file")
+			}
+			i++
+			s, j = lines[i], -1
+		case c == '\\':
+			j++
+			switch s[j] {
+			case '"', '\\':
+				write(s[j])
+			case 'n':
+				write('\n')
+			case 't':
+				write('\t')
+			case 'b':
+				write('\b')
+			default:
+				return "", i, fmt.Errorf("invalid escape \\%c", s[j])
+			}
+		case c == '"':
+			quoted = !quoted
+			value.WriteString(space.String())
+			space.Reset()
+			started = true
+		case quoted:
+			write(c)
+		case c == '#' || c == ';':
+			break scan
+		case c == ' ' || c == '\t':
+			if started {
+				space.WriteByte(c)
+			}
+		default:
+			write(c)
+		}
+	}
+
+	if quoted {
+		return "", i, fmt.Errorf("unterminated quote")
+	}
+	return value.String(), i, nil
+}
+
+// formatValue quotes and escapes value so that parseValue reads it back.
+func formatValue(value string) string {
+	var escaped strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '"', '\\':
+			escaped.WriteByte('\\')
+			escaped.WriteByte(c)
+		case '\n':
+			escaped.WriteString(`\n`)
+		case '\t':
+			escaped.WriteString(`\t`)
+		case '\b':
+			escaped.WriteString(`\b`)
+		default:
+			escaped.WriteByte(c)
+		}
+	}
+
+	result := escaped.String()
+	if value != strings.TrimSpace(value) || strings.ContainsAny(value, "#;") {
+		result = `"` + result + `"`
+	}
+	return result
+}
+
+func isKeyChar(c byte) bool {
+	return c == '-' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// validKey reports whether key is a valid variable name: alphanumeric
+// characters and dashes, starting with a letter.
+func validKey(key string) bool {
+	if key == "" || key[0] == '-' || key[0] >= '0' && key[0] <= '9' {
+		return false
+	}
+	for i := 0; i < len(key); i++ {
+		if !isKeyChar(key[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// splitName splits a "section[.subsection].key" name into its lowercased
+// section, its subsection and its lowercased key.
+func splitName(name string) (string, string, string, error) {
+	first := strings.IndexByte(name, '.')
+	last := strings.LastIndexByte(name, '.')
+	if first <= 0 || last == len(name)-1 {
+		return "", "", "", fmt.Errorf("key does not contain a section: %s", name)
+	}
+
+	section, key := strings.ToLower(name[:first]), strings.ToLower(name[last+1:])
+	for i := 0; i < len(section); i++ {
+		if !isKeyChar(section[i]) {
+			return "", "", "", fmt.Errorf("invalid section name: %s", name)
+		}
+	}
+	if !validKey(key) {
+		return "", "", "", fmt.Errorf("invalid key: %s", name)
+	}
+
+	subsection := ""
+	if first != last {
+		subsection = name[first+1 : last]
+		if strings.Contains(subsection, "\n") {
+			return "", "", "", fmt.Errorf("invalid subsection in %q", name)
+		}
+	}
+	return section, subsection, key, nil
+}
+
+// canonicalName returns name with its section and key lowercased.
+func canonicalName(name string) (string, error) {
+	section, subsection, key, err := splitName(name)
+	if err != nil {
+		return "", err
+	}
+	if subsection != "" {
+		section += "." + subsection
+	}
+	return section + "." + key, nil
+}
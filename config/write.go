@@ -0,0 +1,144 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/nexxeln/mini-git/internal/lockfile"
+)
+
+// Set assigns value to name in the config file at path, creating the file
+// if needed. An existing assignment is replaced in place; otherwise the
+// variable is added to the end of the last matching section, or to a new
+// section. Names with several values cannot be set.
+func Set(path, name, value string) error {
+	section, subsection, key, err := splitName(name)
+	if err != nil {
+		return err
+	}
+	sectionName := section
+	if subsection != "" {
+		sectionName += "." + subsection
+	}
+	line := "\t" + key + " = " + formatValue(value)
+
+	return edit(path, func(doc *document) error {
+		matches := doc.find(sectionName + "." + key)
+		if len(matches) > 1 {
+			return fmt.Errorf("%s has multiple values", name)
+		}
+		if len(matches) == 1 {
+			doc.replace(matches[0].first, matches[0].last, line)
+			return nil
+		}
+
+		for i := len(doc.sections) - 1; i >= 0; i-- {
+			if doc.sections[i].name != sectionName {
+				continue
+			}
+			end := doc.sections[i].line + 1
+			for _, v := range doc.vars {
+				if v.first > doc.sections[i].line && (i+1 == len(doc.sections) || v.first < doc.sections[i+1].line) {
+					end = v.last + 1
+				}
+			}
+			doc.replace(end, end-1, line)
+			return nil
+		}
+
+		headerLine := "[" + section + "]"
+		if subsection != "" {
+			escaped := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(subsection)
+			headerLine = "[" + section + ` "` + escaped + `"]`
+		}
+		doc.lines = append(doc.lines, headerLine, line)
+		return nil
+	})
+}
+
+// Unset removes the assignment of name from the config file at path. It is
+// an error if name is not set or has several values.
+func Unset(path, name string) error {
+	return unset(path, name, false)
+}
+
+// UnsetAll removes every assignment of name from the config file at path.
+func UnsetAll(path, name string) error {
+	return unset(path, name, true)
+}
+
+func unset(path, name string, all bool) error {
+	canonical, err := canonicalName(name)
+	if err != nil {
+		return err
+	}
+
+	return edit(path, func(doc *document) error {
+		matches := doc.find(canonical)
+		if len(matches) == 0 {
+			return fmt.Errorf("%s is not set", name)
+		}
+		if len(matches) > 1 && !all {
+			return fmt.Errorf("%s has multiple values", name)
+		}
+		for i := len(matches) - 1; i >= 0; i-- {
+			doc.replace(matches[i].first, matches[i].last)
+		}
+		return nil
+	})
+}
+
+func (doc *document) find(name string) []variable {
+	var matches []variable
+	for _, v := range doc.vars {
+		if v.name == name {
+			matches = append(matches, v)
+		}
+	}
+	return matches
+}
+
+// replace swaps lines first through last for the given lines. A last of
+// first-1 inserts them before first.
+func (doc *document) replace(first, last int, lines ...string) {
+	rest := append(lines, doc.lines[last+1:]...)
+	doc.lines = append(doc.lines[:first], rest...)
+}
+
+// edit applies change to the config file at path while holding its lock
+// file.
+func edit(path string, change func(*document) error) error {
+	lock, err := lockfile.Lock(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		lock.Rollback()
+		return fmt.Errorf("failed to read config file: %v", err)
+	}
+	doc, err := parseDocument(data)
+	if err != nil {
+		lock.Rollback()
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if err := change(doc); err != nil {
+		lock.Rollback()
+		return err
+	}
+
+	content := strings.Join(doc.lines, "\n")
+	if content != "" {
+		content += "\n"
+	}
+	if _, err := lock.Write([]byte(content)); err != nil {
+		lock.Rollback()
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	if err := lock.Commit(); err != nil {
+		return fmt.Errorf("failed to write config file: %v", err)
+	}
+	return nil
+}
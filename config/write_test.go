@@ -0,0 +1,113 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetPreservesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	writeConfig(t, path, "# keep me\n[core]\n\tbare = false ; and me\n\n[user]\n\tname = Old\n")
+
+	if err := Set(path, "user.name", "New Name"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := Set(path, "core.editor", "vim # not a comment"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if err := Set(path, `remote.My "Repo".url`, `C:\repo`); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	expected := "# keep me\n[core]\n\tbare = false ; and me\n\teditor = \"vim # not a comment\"\n\n[user]\n\tname = New Name\n" +
+		"[remote \"My \\\"Repo\\\"\"]\n\turl = C:\\\\repo\n"
+	if string(data) != expected {
+		t.Errorf("Unexpected config file:\n%s\nexpected:\n%s", data, expected)
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	for name, value := range map[string]string{
+		"user.name":            "New Name",
+		"core.editor":          "vim # not a comment",
+		`remote.My "Repo".url`: `C:\repo`,
+	} {
+		if got, _ := c.Get(name); got != value {
+			t.Errorf("Get(%q) = %q; expected %q", name, got, value)
+		}
+	}
+
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock file to be removed")
+	}
+}
+
+func TestSetCreatesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	if err := Set(path, "user.email", "  padded  "); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if value, _ := c.Get("user.email"); value != "  padded  " {
+		t.Errorf("Expected whitespace to survive a round trip, got %q", value)
+	}
+
+	if err := Set(path, "nosection", "x"); err == nil {
+		t.Errorf("Expected an error for a name without a section")
+	}
+	if err := Set(path, "user.1name", "x"); err == nil {
+		t.Errorf("Expected an error for an invalid key")
+	}
+}
+
+func TestUnset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	writeConfig(t, path, "[alias]\n\tst = status\n\tco = checkout\n\tst = stash \\\n  list\n")
+
+	if err := Unset(path, "alias.st"); err == nil {
+		t.Errorf("Expected an error unsetting a multi-valued key")
+	}
+	if err := Set(path, "alias.st", "x"); err == nil {
+		t.Errorf("Expected an error setting a multi-valued key")
+	}
+	if err := UnsetAll(path, "alias.st"); err != nil {
+		t.Fatalf("UnsetAll failed: %v", err)
+	}
+	if err := Unset(path, "alias.co"); err != nil {
+		t.Fatalf("Unset failed: %v", err)
+	}
+	if err := Unset(path, "alias.co"); err == nil {
+		t.Errorf("Expected an error unsetting a missing key")
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read config: %v", err)
+	}
+	if string(data) != "[alias]\n" {
+		t.Errorf("Unexpected config file %q", data)
+	}
+}
+
+func TestEditLocked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config")
+	writeConfig(t, path+".lock", "")
+
+	if err := Set(path, "user.name", "x"); err == nil {
+		t.Fatalf("Expected an error while the config is locked")
+	}
+	if _, err := os.Stat(path + ".lock"); err != nil {
+		t.Errorf("Expected another process's lock file to be left alone")
+	}
+}
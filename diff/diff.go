@@ -0,0 +1,144 @@
+package diff
+
+import (
+	"bytes"
+
+	"github.com/nexxeln/mini-git/blob"
+)
+
+type Op int
+
+const (
+	OpEqual Op = iota
+	OpInsert
+	OpDelete
+)
+
+// Edit is a single line of an edit script. OldLine and NewLine are the
+// zero-based positions in the old and new input at which the edit applies.
+type Edit struct {
+	Op      Op
+	OldLine int
+	NewLine int
+	Text    string
+}
+
+type Result struct {
+	Edits []Edit
+	Hunks []Hunk
+}
+
+// SplitLines splits content into lines, keeping each line's terminating
+// newline so that a missing newline at end of file shows up as a change.
+func SplitLines(content []byte) []string {
+	if len(content) == 0 {
+		return nil
+	}
+
+	parts := bytes.SplitAfter(content, []byte("\n"))
+	lines := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if len(part) > 0 {
+			lines = append(lines, string(part))
+		}
+	}
+	return lines
+}
+
+// Lines computes a shortest edit script turning a into b using Myers'
+// O((N+M)D) algorithm.
+func Lines(a, b []string) []Edit {
+	return Compute(a, b, DefaultOptions())
+}
+
+// Compute returns an edit script turning a into b using the algorithm and
+// whitespace handling selected in opts. Lines are compared after whitespace
+// normalization, but edits always carry the original text; unchanged lines
+// carry the new side's text.
+func Compute(a, b []string, opts Options) []Edit {
+	keysA := normalizeLines(a, opts.Whitespace)
+	keysB := normalizeLines(b, opts.Whitespace)
+
+	var edits []Edit
+	switch opts.Algorithm {
+	case AlgorithmPatience:
+		edits = refine(keysA, keysB, 0, len(keysA), 0, len(keysB), patienceAnchors, nil)
+	case AlgorithmHistogram:
+		edits = refine(keysA, keysB, 0, len(keysA), 0, len(keysB), histogramAnchors, nil)
+	default:
+		edits = myers(keysA, keysB, 0, len(keysA), 0, len(keysB), nil)
+	}
+
+	for i, edit := range edits {
+		switch edit.Op {
+		case OpDelete:
+			edits[i].Text = a[edit.OldLine]
+		default:
+			edits[i].Text = b[edit.NewLine]
+		}
+	}
+	return edits
+}
+
+// Blobs diffs the contents of two blobs. Either blob may be nil to stand for
+// a file that does not exist on that side.
+func Blobs(oldBlob, newBlob *blob.Blob, opts Options) *Result {
+	var oldContent, newContent []byte
+	if oldBlob != nil {
+		oldContent = oldBlob.Content
+	}
+	if newBlob != nil {
+		newContent = newBlob.Content
+	}
+
+	edits := Compute(SplitLines(oldContent), SplitLines(newContent), opts)
+	return &Result{
+		Edits: edits,
+		Hunks: Hunks(edits, opts.Context),
+	}
+}
+
+// match is a run of length lines that are equal in both inputs, starting at
+// a[A] and b[B].
+type match struct {
+	A, B, Length int
+}
+
+type anchorFunc func(a, b []string, aLo, aHi, bLo, bHi int) []match
+
+// refine diffs a[aLo:aHi] against b[bLo:bHi] by trimming common prefix and
+// suffix, pinning the ranges at the matches chosen by anchors and recursing
+// into the gaps between them. Ranges without anchors fall back to Myers.
+func refine(a, b []string, aLo, aHi, bLo, bHi int, anchors anchorFunc, edits []Edit) []Edit {
+	for aLo < aHi && bLo < bHi && a[aLo] == b[bLo] {
+		edits = append(edits, Edit{Op: OpEqual, OldLine: aLo, NewLine: bLo})
+		aLo++
+		bLo++
+	}
+
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && a[aHi-1-suffix] == b[bHi-1-suffix] {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	matches := anchors(a, b, aLo, aHi, bLo, bHi)
+	if len(matches) == 0 {
+		edits = myers(a, b, aLo, aHi, bLo, bHi, edits)
+	} else {
+		for _, m := range matches {
+			edits = refine(a, b, aLo, m.A, bLo, m.B, anchors, edits)
+			for i := 0; i < m.Length; i++ {
+				edits = append(edits, Edit{Op: OpEqual, OldLine: m.A + i, NewLine: m.B + i})
+			}
+			aLo, bLo = m.A+m.Length, m.B+m.Length
+		}
+		edits = refine(a, b, aLo, aHi, bLo, bHi, anchors, edits)
+	}
+
+	for i := 0; i < suffix; i++ {
+		edits = append(edits, Edit{Op: OpEqual, OldLine: aHi + i, NewLine: bHi + i})
+	}
+	return edits
+}
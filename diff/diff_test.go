@@ -0,0 +1,303 @@
+package diff
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/nexxeln/mini-git/blob"
+)
+
+func applyEdits(t *testing.T, a []string, edits []Edit) []string {
+	t.Helper()
+
+	var result []string
+	for _, edit := range edits {
+		switch edit.Op {
+		case OpEqual:
+			if a[edit.OldLine] != edit.Text {
+				t.Fatalf("Equal edit at old line %d does not match input", edit.OldLine)
+			}
+			result = append(result, edit.Text)
+		case OpInsert:
+			result = append(result, edit.Text)
+		case OpDelete:
+			if a[edit.OldLine] != edit.Text {
+				t.Fatalf("Delete edit at old line %d does not match input", edit.OldLine)
+			}
+		}
+	}
+	return result
+}
+
+func TestLinesProducesShortestScript(t *testing.T) {
+	a := strings.Split("a b c a b b a", " ")
+	b := strings.Split("c b a b a c", " ")
+
+	edits := Lines(a, b)
+
+	changes := 0
+	for _, edit := range edits {
+		if edit.Op != OpEqual {
+			changes++
+		}
+	}
+	if changes != 5 {
+		t.Errorf("Expected an edit script with 5 changes, got %d", changes)
+	}
+
+	result := applyEdits(t, a, edits)
+	if strings.Join(result, " ") != strings.Join(b, " ") {
+		t.Errorf("Applying edits gave %v, expected %v", result, b)
+	}
+}
+
+// lcsLength returns the length of the longest common subsequence of a and
+// b, which a shortest edit script keeps unchanged.
+func lcsLength(a, b []string) int {
+	row := make([]int, len(b)+1)
+	for i := range a {
+		prev := 0
+		for j := range b {
+			next := row[j+1]
+			if a[i] == b[j] {
+				row[j+1] = prev + 1
+			} else if row[j] > row[j+1] {
+				row[j+1] = row[j]
+			}
+			prev = next
+		}
+	}
+	return row[len(b)]
+}
+
+func TestLinesShortestOnRandomInputs(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		a, b := make([]string, r.Intn(20)), make([]string, r.Intn(20))
+		letters := 1 + r.Intn(4)
+		for j := range a {
+			a[j] = string(rune('a' + r.Intn(letters)))
+		}
+		for j := range b {
+			b[j] = string(rune('a' + r.Intn(letters)))
+		}
+
+		edits := Lines(a, b)
+		result := applyEdits(t, a, edits)
+		if strings.Join(result, " ") != strings.Join(b, " ") {
+			t.Fatalf("Applying edits for %v -> %v gave %v", a, b, result)
+		}
+		kept := 0
+		for _, edit := range edits {
+			if edit.Op == OpEqual {
+				kept++
+			}
+		}
+		if kept != lcsLength(a, b) {
+			t.Fatalf("Edit script for %v -> %v is not shortest: %v", a, b, edits)
+		}
+	}
+}
+
+func TestLinesRewrittenFile(t *testing.T) {
+	a, b := make([]string, 5000), make([]string, 5000)
+	for i := range a {
+		a[i] = fmt.Sprintf("old %d\n", i)
+		b[i] = fmt.Sprintf("new %d\n", i)
+	}
+
+	edits := Lines(a, b)
+	if len(edits) != len(a)+len(b) {
+		t.Errorf("Expected %d edits, got %d", len(a)+len(b), len(edits))
+	}
+	result := applyEdits(t, a, edits)
+	if strings.Join(result, "") != strings.Join(b, "") {
+		t.Errorf("Applying edits did not give the rewritten file")
+	}
+}
+
+func TestLinesEmptyInputs(t *testing.T) {
+	if edits := Lines(nil, nil); len(edits) != 0 {
+		t.Errorf("Expected no edits for empty inputs, got %v", edits)
+	}
+
+	edits := Lines(nil, []string{"x\n", "y\n"})
+	if len(edits) != 2 || edits[0].Op != OpInsert || edits[1].Op != OpInsert {
+		t.Errorf("Expected two inserts, got %v", edits)
+	}
+
+	edits = Lines([]string{"x\n"}, nil)
+	if len(edits) != 1 || edits[0].Op != OpDelete {
+		t.Errorf("Expected one delete, got %v", edits)
+	}
+}
+
+func TestSplitLines(t *testing.T) {
+	lines := SplitLines([]byte("one\ntwo\nthree"))
+	expected := []string{"one\n", "two\n", "three"}
+
+	if len(lines) != len(expected) {
+		t.Fatalf("Expected %d lines, got %d", len(expected), len(lines))
+	}
+	for i := range expected {
+		if lines[i] != expected[i] {
+			t.Errorf("Line %d: expected %q, got %q", i, expected[i], lines[i])
+		}
+	}
+}
+
+func TestUnified(t *testing.T) {
+	oldContent := []byte("1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n")
+	newContent := []byte("1\n2\nthree\n4\n5\n6\n7\n8\n9\n10\n11\n")
+
+	var out bytes.Buffer
+	if err := Unified(&out, "a/file.txt", "b/file.txt", oldContent, newContent, DefaultOptions()); err != nil {
+		t.Fatalf("Failed to write diff: %v", err)
+	}
+
+	expected := `--- a/file.txt
++++ b/file.txt
+@@ -1,6 +1,6 @@
+ 1
+ 2
+-3
++three
+ 4
+ 5
+ 6
+@@ -8,3 +8,4 @@
+ 8
+ 9
+ 10
++11
+`
+	if out.String() != expected {
+		t.Errorf("Unified diff does not match.\nExpected:\n%s\nGot:\n%s", expected, out.String())
+	}
+}
+
+func TestUnifiedNewFileAndMissingNewline(t *testing.T) {
+	var out bytes.Buffer
+	if err := Unified(&out, "/dev/null", "b/file.txt", nil, []byte("hello"), DefaultOptions()); err != nil {
+		t.Fatalf("Failed to write diff: %v", err)
+	}
+
+	expected := "--- /dev/null\n+++ b/file.txt\n@@ -0,0 +1 @@\n+hello\n\\ No newline at end of file\n"
+	if out.String() != expected {
+		t.Errorf("Unified diff does not match.\nExpected:\n%q\nGot:\n%q", expected, out.String())
+	}
+}
+
+func TestUnifiedIdenticalContent(t *testing.T) {
+	var out bytes.Buffer
+	if err := Unified(&out, "a/file.txt", "b/file.txt", []byte("same\n"), []byte("same\n"), DefaultOptions()); err != nil {
+		t.Fatalf("Failed to write diff: %v", err)
+	}
+
+	if out.Len() != 0 {
+		t.Errorf("Expected no output for identical content, got %q", out.String())
+	}
+}
+
+func TestAlgorithmsProduceValidScripts(t *testing.T) {
+	inputs := [][2]string{
+		{"a b c a b b a", "c b a b a c"},
+		{"x y z", "x y z"},
+		{"", "p q r"},
+		{"p q r", ""},
+		{"{ a } { b } { c }", "{ a } { x } { b } { c }"},
+		{"a a a b b b", "b b b a a a"},
+	}
+	algorithms := []Algorithm{AlgorithmMyers, AlgorithmPatience, AlgorithmHistogram}
+
+	for _, input := range inputs {
+		a, b := strings.Fields(input[0]), strings.Fields(input[1])
+		for _, algorithm := range algorithms {
+			opts := DefaultOptions()
+			opts.Algorithm = algorithm
+
+			result := applyEdits(t, a, Compute(a, b, opts))
+			if strings.Join(result, " ") != strings.Join(b, " ") {
+				t.Errorf("Algorithm %d on %q -> %q: applying edits gave %v", algorithm, input[0], input[1], result)
+			}
+		}
+	}
+}
+
+func TestPatienceMatchesUniqueLines(t *testing.T) {
+	a := []string{"}\n", "func a() {\n", "}\n", "func b() {\n", "}\n"}
+	b := []string{"}\n", "func c() {\n", "}\n", "func a() {\n", "}\n", "func b() {\n", "}\n"}
+
+	opts := DefaultOptions()
+	opts.Algorithm = AlgorithmPatience
+
+	for _, edit := range Compute(a, b, opts) {
+		if strings.HasPrefix(edit.Text, "func a") || strings.HasPrefix(edit.Text, "func b") {
+			if edit.Op != OpEqual {
+				t.Errorf("Expected unique line %q to be unchanged, got op %d", edit.Text, edit.Op)
+			}
+		}
+	}
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	for name, expected := range map[string]Algorithm{"myers": AlgorithmMyers, "patience": AlgorithmPatience, "histogram": AlgorithmHistogram} {
+		algorithm, err := ParseAlgorithm(name)
+		if err != nil || algorithm != expected {
+			t.Errorf("ParseAlgorithm(%q) = %d, %v; expected %d", name, algorithm, err, expected)
+		}
+	}
+
+	if _, err := ParseAlgorithm("minimal-ish"); err == nil {
+		t.Errorf("Expected an error for an unknown algorithm")
+	}
+}
+
+func TestWhitespaceOptions(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		mode     Whitespace
+		expected bool
+	}{
+		{"a b\n", "a b  \n", WhitespaceExact, false},
+		{"a b\n", "a b  \n", WhitespaceIgnoreAtEOL, true},
+		{"a  b\n", "a b\n", WhitespaceIgnoreAtEOL, false},
+		{"a  b\n", "a\tb \n", WhitespaceIgnoreChange, true},
+		{"ab\n", "a b\n", WhitespaceIgnoreChange, false},
+		{"ab\n", " a b\n", WhitespaceIgnoreAll, true},
+		{"ab\n", "ab", WhitespaceIgnoreAll, false},
+	}
+
+	for _, test := range tests {
+		opts := DefaultOptions()
+		opts.Whitespace = test.mode
+
+		edits := Compute([]string{test.a}, []string{test.b}, opts)
+		equal := len(edits) == 1 && edits[0].Op == OpEqual
+		if equal != test.expected {
+			t.Errorf("Mode %d: %q vs %q equal = %v, expected %v", test.mode, test.a, test.b, equal, test.expected)
+		}
+		if equal && edits[0].Text != test.b {
+			t.Errorf("Mode %d: unchanged line should carry the new text %q, got %q", test.mode, test.b, edits[0].Text)
+		}
+	}
+}
+
+func TestBlobs(t *testing.T) {
+	newBlob, _ := blob.NewBlob([]byte("one\ntwo\n"))
+
+	result := Blobs(nil, newBlob, DefaultOptions())
+
+	if len(result.Edits) != 2 || result.Edits[0].Op != OpInsert || result.Edits[1].Op != OpInsert {
+		t.Errorf("Expected two inserts, got %v", result.Edits)
+	}
+	if len(result.Hunks) != 1 {
+		t.Fatalf("Expected one hunk, got %d", len(result.Hunks))
+	}
+	if header := result.Hunks[0].Header(); header != "@@ -0,0 +1,2 @@" {
+		t.Errorf("Unexpected hunk header %q", header)
+	}
+}
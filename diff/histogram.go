@@ -0,0 +1,62 @@
+package diff
+
+// maxChainLength bounds how often a line may occur in the old range and still
+// be considered as an anchor, as in Git's histogram diff.
+const maxChainLength = 64
+
+// histogramAnchors picks the run of common lines whose rarest line occurs the
+// fewest times in the old range, preferring longer runs on ties. Unlike
+// patience diff, lines do not have to be unique to serve as anchors.
+func histogramAnchors(a, b []string, aLo, aHi, bLo, bHi int) []match {
+	positions := make(map[string][]int)
+	for i := aLo; i < aHi; i++ {
+		positions[a[i]] = append(positions[a[i]], i)
+	}
+
+	var best match
+	bestCount := maxChainLength + 1
+
+	for j := bLo; j < bHi; {
+		next := j + 1
+
+		candidates := positions[b[j]]
+		if len(candidates) > 0 && len(candidates) <= maxChainLength && len(candidates) <= bestCount {
+			for _, i := range candidates {
+				aStart, bStart := i, j
+				for aStart > aLo && bStart > bLo && a[aStart-1] == b[bStart-1] {
+					aStart--
+					bStart--
+				}
+				aEnd, bEnd := i+1, j+1
+				for aEnd < aHi && bEnd < bHi && a[aEnd] == b[bEnd] {
+					aEnd++
+					bEnd++
+				}
+
+				count := bestCount + 1
+				for k := aStart; k < aEnd; k++ {
+					if c := len(positions[a[k]]); c < count {
+						count = c
+					}
+				}
+
+				length := aEnd - aStart
+				if count < bestCount || (count == bestCount && length > best.Length) {
+					best = match{A: aStart, B: bStart, Length: length}
+					bestCount = count
+				}
+
+				if bEnd > next {
+					next = bEnd
+				}
+			}
+		}
+
+		j = next
+	}
+
+	if best.Length == 0 {
+		return nil
+	}
+	return []match{best}
+}
@@ -0,0 +1,135 @@
+package diff
+
+// myers appends to edits a shortest edit script turning a[aLo:aHi] into
+// b[bLo:bHi], found with Myers' O((N+M)D) algorithm in its linear space
+// form: rather than keeping every step of the search to trace the path
+// back, it finds a point on a shortest path by searching from both ends at
+// once, and diffs the two halves on either side of it recursively.
+func myers(a, b []string, aLo, aHi, bLo, bHi int, edits []Edit) []Edit {
+	size := aHi - aLo + bHi - bLo + 3
+	s := &myersSearch{a: a, b: b, forward: make([]int, size), backward: make([]int, size)}
+	return s.diff(aLo, aHi, bLo, bHi, edits)
+}
+
+// myersSearch holds the lines being compared and the vectors of furthest
+// reaching paths, which are shared by every level of the recursion.
+type myersSearch struct {
+	a, b              []string
+	forward, backward []int
+}
+
+func (s *myersSearch) diff(aLo, aHi, bLo, bHi int, edits []Edit) []Edit {
+	for aLo < aHi && bLo < bHi && s.a[aLo] == s.b[bLo] {
+		edits = append(edits, Edit{Op: OpEqual, OldLine: aLo, NewLine: bLo})
+		aLo++
+		bLo++
+	}
+	suffix := 0
+	for aLo < aHi-suffix && bLo < bHi-suffix && s.a[aHi-suffix-1] == s.b[bHi-suffix-1] {
+		suffix++
+	}
+	aHi -= suffix
+	bHi -= suffix
+
+	switch {
+	case aLo == aHi:
+		for y := bLo; y < bHi; y++ {
+			edits = append(edits, Edit{Op: OpInsert, OldLine: aLo, NewLine: y})
+		}
+	case bLo == bHi:
+		for x := aLo; x < aHi; x++ {
+			edits = append(edits, Edit{Op: OpDelete, OldLine: x, NewLine: bLo})
+		}
+	default:
+		x, y := s.split(aLo, aHi, bLo, bHi)
+		edits = s.diff(aLo, x, bLo, y, edits)
+		edits = s.diff(x, aHi, y, bHi, edits)
+	}
+
+	for i := 0; i < suffix; i++ {
+		edits = append(edits, Edit{Op: OpEqual, OldLine: aHi + i, NewLine: bHi + i})
+	}
+	return edits
+}
+
+// split returns a point on a shortest path from (aLo, bLo) to (aHi, bHi),
+// neither of them, where paths extended forward from the start and backward
+// from the end d edits at a time first overlap. Both ranges must be
+// non-empty and differ in their first and last lines, so the path has at
+// least two edits and each half has fewer than the whole.
+func (s *myersSearch) split(aLo, aHi, bLo, bHi int) (int, int) {
+	n, m := aHi-aLo, bHi-bLo
+	delta := n - m
+	maxD := (n + m + 1) / 2
+	offset := maxD
+
+	// forward[offset+k] is the furthest x reached on diagonal k = x-y from
+	// the start, and backward[offset+k] the same from the end, with x and y
+	// counted back from aHi and bHi. -1 marks diagonals not reached yet.
+	vf, vb := s.forward[:2*maxD+2], s.backward[:2*maxD+2]
+	for i := range vf {
+		vf[i], vb[i] = -1, -1
+	}
+	vf[offset+1], vb[offset+1] = 0, 0
+
+	// Diagonals whose paths have left the grid are skipped from then on.
+	fStart, fEnd, bStart, bEnd := 0, 0, 0, 0
+	for d := 0; d <= maxD; d++ {
+		for k := -d + fStart; k <= d-fEnd; k += 2 {
+			var x int
+			if k == -d || k != d && vf[offset+k-1] < vf[offset+k+1] {
+				x = vf[offset+k+1]
+			} else {
+				x = vf[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && s.a[aLo+x] == s.b[bLo+y] {
+				x++
+				y++
+			}
+			vf[offset+k] = x
+
+			switch {
+			case x > n:
+				fEnd += 2
+			case y > m:
+				fStart += 2
+			case delta%2 != 0:
+				// With an odd delta the paths can only meet on a forward step.
+				if c := offset + delta - k; c >= 0 && c < len(vb) && vb[c] != -1 && x >= n-vb[c] {
+					return aLo + x, bLo + y
+				}
+			}
+		}
+
+		for k := -d + bStart; k <= d-bEnd; k += 2 {
+			var x int
+			if k == -d || k != d && vb[offset+k-1] < vb[offset+k+1] {
+				x = vb[offset+k+1]
+			} else {
+				x = vb[offset+k-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && s.a[aHi-x-1] == s.b[bHi-y-1] {
+				x++
+				y++
+			}
+			vb[offset+k] = x
+
+			switch {
+			case x > n:
+				bEnd += 2
+			case y > m:
+				bStart += 2
+			case delta%2 == 0:
+				if c := offset + delta - k; c >= 0 && c < len(vf) && vf[c] != -1 && vf[c] >= n-x {
+					return aLo + vf[c], bLo + vf[c] - (c - offset)
+				}
+			}
+		}
+	}
+
+	// Not reached: the searches meet once d is half the length of a shortest
+	// path. Splitting off the first half of a still makes progress.
+	return aLo + (n+1)/2, bLo
+}
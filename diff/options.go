@@ -0,0 +1,103 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Algorithm int
+
+const (
+	AlgorithmMyers Algorithm = iota
+	AlgorithmPatience
+	AlgorithmHistogram
+)
+
+type Whitespace int
+
+const (
+	// WhitespaceExact compares lines byte for byte.
+	WhitespaceExact Whitespace = iota
+	// WhitespaceIgnoreAtEOL ignores whitespace before the end of a line.
+	WhitespaceIgnoreAtEOL
+	// WhitespaceIgnoreChange treats runs of whitespace as a single space and
+	// ignores whitespace at the end of a line.
+	WhitespaceIgnoreChange
+	// WhitespaceIgnoreAll ignores whitespace entirely.
+	WhitespaceIgnoreAll
+)
+
+type Options struct {
+	Algorithm  Algorithm
+	Context    int
+	Whitespace Whitespace
+}
+
+func DefaultOptions() Options {
+	return Options{
+		Algorithm: AlgorithmMyers,
+		Context:   3,
+	}
+}
+
+func ParseAlgorithm(name string) (Algorithm, error) {
+	switch name {
+	case "myers", "default":
+		return AlgorithmMyers, nil
+	case "patience":
+		return AlgorithmPatience, nil
+	case "histogram":
+		return AlgorithmHistogram, nil
+	}
+	return AlgorithmMyers, fmt.Errorf("unknown diff algorithm: %s", name)
+}
+
+func normalizeLines(lines []string, mode Whitespace) []string {
+	if mode == WhitespaceExact {
+		return lines
+	}
+
+	keys := make([]string, len(lines))
+	for i, line := range lines {
+		keys[i] = normalizeLine(line, mode)
+	}
+	return keys
+}
+
+// normalizeLine rewrites a line into the key it is compared by. The line
+// terminator is kept so a missing newline at end of file is still a change.
+func normalizeLine(line string, mode Whitespace) string {
+	terminator := ""
+	if strings.HasSuffix(line, "\n") {
+		terminator = "\n"
+		line = line[:len(line)-1]
+	}
+
+	switch mode {
+	case WhitespaceIgnoreAtEOL:
+		line = strings.TrimRight(line, " \t\r\f\v")
+	case WhitespaceIgnoreChange:
+		var collapsed strings.Builder
+		inSpace := false
+		for i := 0; i < len(line); i++ {
+			if isSpace(line[i]) {
+				inSpace = true
+				continue
+			}
+			if inSpace {
+				collapsed.WriteByte(' ')
+				inSpace = false
+			}
+			collapsed.WriteByte(line[i])
+		}
+		line = collapsed.String()
+	case WhitespaceIgnoreAll:
+		line = strings.Join(strings.Fields(line), "")
+	}
+
+	return line + terminator
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v'
+}
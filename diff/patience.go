@@ -0,0 +1,77 @@
+package diff
+
+import (
+	"sort"
+)
+
+// patienceAnchors pairs up lines that occur exactly once in each range and
+// keeps the longest subset of those pairs that appears in the same order on
+// both sides.
+func patienceAnchors(a, b []string, aLo, aHi, bLo, bHi int) []match {
+	type occurrence struct {
+		aCount, bCount int
+		aPos, bPos     int
+	}
+
+	occurrences := make(map[string]*occurrence)
+	for i := aLo; i < aHi; i++ {
+		o, exists := occurrences[a[i]]
+		if !exists {
+			o = &occurrence{}
+			occurrences[a[i]] = o
+		}
+		o.aCount++
+		o.aPos = i
+	}
+	for j := bLo; j < bHi; j++ {
+		if o, exists := occurrences[b[j]]; exists {
+			o.bCount++
+			o.bPos = j
+		}
+	}
+
+	var unique []match
+	for _, o := range occurrences {
+		if o.aCount == 1 && o.bCount == 1 {
+			unique = append(unique, match{A: o.aPos, B: o.bPos, Length: 1})
+		}
+	}
+	sort.Slice(unique, func(i, j int) bool {
+		return unique[i].A < unique[j].A
+	})
+
+	return longestIncreasing(unique)
+}
+
+// longestIncreasing returns the longest subsequence of matches (already
+// ordered by A) whose B positions are increasing, using patience sorting.
+func longestIncreasing(matches []match) []match {
+	if len(matches) == 0 {
+		return nil
+	}
+
+	var tops []int
+	previous := make([]int, len(matches))
+	for i, m := range matches {
+		pile := sort.Search(len(tops), func(p int) bool {
+			return matches[tops[p]].B > m.B
+		})
+
+		previous[i] = -1
+		if pile > 0 {
+			previous[i] = tops[pile-1]
+		}
+
+		if pile == len(tops) {
+			tops = append(tops, i)
+		} else {
+			tops[pile] = i
+		}
+	}
+
+	result := make([]match, len(tops))
+	for i, n := tops[len(tops)-1], len(tops)-1; i >= 0; i, n = previous[i], n-1 {
+		result[n] = matches[i]
+	}
+	return result
+}
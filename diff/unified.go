@@ -0,0 +1,129 @@
+package diff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Edits    []Edit
+}
+
+// Hunks groups the changes of an edit script into hunks, keeping up to
+// context unchanged lines around each change. Changes separated by no more
+// than twice the context share a hunk.
+func Hunks(edits []Edit, context int) []Hunk {
+	var hunks []Hunk
+
+	i := 0
+	for i < len(edits) {
+		if edits[i].Op == OpEqual {
+			i++
+			continue
+		}
+
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+
+		end := i
+		for j := i; j < len(edits); j++ {
+			if edits[j].Op == OpEqual {
+				continue
+			}
+			if j-end > 2*context {
+				break
+			}
+			end = j + 1
+		}
+
+		stop := end + context
+		if stop > len(edits) {
+			stop = len(edits)
+		}
+
+		hunk := Hunk{
+			OldStart: edits[start].OldLine,
+			NewStart: edits[start].NewLine,
+			Edits:    edits[start:stop],
+		}
+		for _, edit := range hunk.Edits {
+			if edit.Op != OpInsert {
+				hunk.OldLines++
+			}
+			if edit.Op != OpDelete {
+				hunk.NewLines++
+			}
+		}
+		hunks = append(hunks, hunk)
+
+		i = stop
+	}
+
+	return hunks
+}
+
+// Header formats the hunk's "@@ -l,s +l,s @@" line using one-based line
+// numbers, as unified diffs do.
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", formatRange(h.OldStart, h.OldLines), formatRange(h.NewStart, h.NewLines))
+}
+
+func formatRange(start, lines int) string {
+	if lines == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if lines == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, lines)
+}
+
+// Unified writes a unified diff of the two contents. Nothing is written when
+// they are identical.
+func Unified(w io.Writer, oldName, newName string, oldContent, newContent []byte, opts Options) error {
+	hunks := Hunks(Compute(SplitLines(oldContent), SplitLines(newContent), opts), opts.Context)
+	if len(hunks) == 0 {
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(w, "--- %s\n+++ %s\n", oldName, newName); err != nil {
+		return err
+	}
+
+	return WriteHunks(w, hunks)
+}
+
+// WriteHunks writes hunks in unified format, without file headers.
+func WriteHunks(w io.Writer, hunks []Hunk) error {
+	for _, hunk := range hunks {
+		if _, err := fmt.Fprintln(w, hunk.Header()); err != nil {
+			return err
+		}
+		for _, edit := range hunk.Edits {
+			prefix := " "
+			switch edit.Op {
+			case OpInsert:
+				prefix = "+"
+			case OpDelete:
+				prefix = "-"
+			}
+
+			line := prefix + edit.Text
+			if !strings.HasSuffix(edit.Text, "\n") {
+				line += "\n\\ No newline at end of file\n"
+			}
+			if _, err := io.WriteString(w, line); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
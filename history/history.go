@@ -0,0 +1,171 @@
+package history
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/nexxeln/mini-git/commit"
+	"github.com/nexxeln/mini-git/objects"
+)
+
+// MergeBases returns the best common ancestors of the given commits: commits
+// reachable from every input that are not themselves ancestors of another
+// common ancestor. Criss-cross histories can have more than one. The result is
+// ordered newest first.
+func MergeBases(db *objects.Database, hashes ...string) ([]string, error) {
+	if len(hashes) == 0 {
+		return nil, fmt.Errorf("no commits given")
+	}
+
+	commits := make(map[string]*commit.Commit)
+	common, err := ancestors(db, hashes[0], commits)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, hash := range hashes[1:] {
+		reachable, err := ancestors(db, hash, commits)
+		if err != nil {
+			return nil, err
+		}
+		for candidate := range common {
+			if !reachable[candidate] {
+				delete(common, candidate)
+			}
+		}
+	}
+
+	// Every ancestor of a common ancestor is common too, so walking down from
+	// the parents of all common commits marks exactly the redundant ones.
+	redundant := make(map[string]bool)
+	var pending []string
+	for candidate := range common {
+		pending = append(pending, commits[candidate].ParentHashes...)
+	}
+	for len(pending) > 0 {
+		hash := pending[0]
+		pending = pending[1:]
+		if redundant[hash] {
+			continue
+		}
+		redundant[hash] = true
+		pending = append(pending, commits[hash].ParentHashes...)
+	}
+
+	var bases []string
+	for candidate := range common {
+		if !redundant[candidate] {
+			bases = append(bases, candidate)
+		}
+	}
+
+	sort.Slice(bases, func(i, j int) bool {
+		di, dj := commits[bases[i]].CommitDate, commits[bases[j]].CommitDate
+		if !di.Equal(dj) {
+			return di.After(dj)
+		}
+		return bases[i] < bases[j]
+	})
+
+	return bases, nil
+}
+
+// IsAncestor reports whether possibleAncestor is reachable from hash by
+// following parent links. A commit is considered its own ancestor.
+func IsAncestor(db *objects.Database, possibleAncestor, hash string) (bool, error) {
+	seen := make(map[string]bool)
+	pending := []string{hash}
+
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		if current == "" || seen[current] {
+			continue
+		}
+		seen[current] = true
+
+		if current == possibleAncestor {
+			return true, nil
+		}
+
+		c, err := db.Commit(current)
+		if err != nil {
+			return false, fmt.Errorf("failed to retrieve commit %s: %v", current, err)
+		}
+		pending = append(pending, c.ParentHashes...)
+	}
+
+	return false, nil
+}
+
+func ancestors(db *objects.Database, hash string, commits map[string]*commit.Commit) (map[string]bool, error) {
+	reachable := make(map[string]bool)
+	pending := []string{hash}
+
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		if current == "" || reachable[current] {
+			continue
+		}
+		reachable[current] = true
+
+		c, loaded := commits[current]
+		if !loaded {
+			var err error
+			c, err = db.Commit(current)
+			if err != nil {
+				return nil, fmt.Errorf("failed to retrieve commit %s: %v", current, err)
+			}
+			commits[current] = c
+		}
+		pending = append(pending, c.ParentHashes...)
+	}
+
+	return reachable, nil
+}
+
+// Unreachable returns the commits reachable from hash that no commit in
+// from can reach, starting with hash itself.
+func Unreachable(db *objects.Database, hash string, from []string) ([]string, error) {
+	commits := make(map[string]*commit.Commit)
+	reachable := make(map[string]bool)
+	for _, start := range from {
+		if reachable[start] {
+			continue
+		}
+		found, err := ancestors(db, start, commits)
+		if err != nil {
+			return nil, err
+		}
+		for h := range found {
+			reachable[h] = true
+		}
+	}
+
+	var lost []string
+	seen := make(map[string]bool)
+	pending := []string{hash}
+	for len(pending) > 0 {
+		current := pending[0]
+		pending = pending[1:]
+		if current == "" || seen[current] || reachable[current] {
+			continue
+		}
+		seen[current] = true
+		lost = append(lost, current)
+
+		c, loaded := commits[current]
+		if !loaded {
+			var err error
+			c, err = db.Commit(current)
+			if err != nil {
+				return nil, fmt.Errorf("failed to retrieve commit %s: %v", current, err)
+			}
+			commits[current] = c
+		}
+		pending = append(pending, c.ParentHashes...)
+	}
+
+	return lost, nil
+}
@@ -0,0 +1,167 @@
+package history
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nexxeln/mini-git/commit"
+	"github.com/nexxeln/mini-git/objects"
+	"github.com/nexxeln/mini-git/tree"
+)
+
+var clock = time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+
+func storeCommit(t *testing.T, db *objects.Database, message string, parents ...string) string {
+	t.Helper()
+
+	clock = clock.Add(time.Minute)
+	c := commit.NewCommit("0123456789abcdef0123456789abcdef01234567", parents, "John Doe <john@example.com>", "John Doe <john@example.com>", message)
+	c.AuthorDate = clock
+	c.CommitDate = clock
+
+	hash, err := db.Put(c)
+	if err != nil {
+		t.Fatalf("Failed to store commit: %v", err)
+	}
+	return hash
+}
+
+func newRepo() *objects.Database {
+	return objects.NewMemoryDatabase(objects.FormatMiniGit, tree.EncodingText)
+}
+
+func TestMergeBasesForkedHistory(t *testing.T) {
+	repo := newRepo()
+	root := storeCommit(t, repo, "root")
+	base := storeCommit(t, repo, "base", root)
+	ours := storeCommit(t, repo, "ours", base)
+	theirs := storeCommit(t, repo, "theirs", base)
+
+	bases, err := MergeBases(repo, ours, theirs)
+	if err != nil {
+		t.Fatalf("Failed to compute merge bases: %v", err)
+	}
+
+	if len(bases) != 1 || bases[0] != base {
+		t.Errorf("Expected merge base [%s], got %v", base, bases)
+	}
+}
+
+func TestMergeBasesOfAncestor(t *testing.T) {
+	repo := newRepo()
+	root := storeCommit(t, repo, "root")
+	child := storeCommit(t, repo, "child", root)
+
+	bases, err := MergeBases(repo, child, root)
+	if err != nil {
+		t.Fatalf("Failed to compute merge bases: %v", err)
+	}
+
+	if len(bases) != 1 || bases[0] != root {
+		t.Errorf("Expected merge base [%s], got %v", root, bases)
+	}
+}
+
+func TestMergeBasesCrissCross(t *testing.T) {
+	repo := newRepo()
+	root := storeCommit(t, repo, "root")
+	a1 := storeCommit(t, repo, "a1", root)
+	b1 := storeCommit(t, repo, "b1", root)
+	a2 := storeCommit(t, repo, "a2", a1, b1)
+	b2 := storeCommit(t, repo, "b2", b1, a1)
+
+	bases, err := MergeBases(repo, a2, b2)
+	if err != nil {
+		t.Fatalf("Failed to compute merge bases: %v", err)
+	}
+
+	if len(bases) != 2 || bases[0] != b1 || bases[1] != a1 {
+		t.Errorf("Expected merge bases [%s %s], got %v", b1, a1, bases)
+	}
+}
+
+func TestMergeBasesOfThreeCommits(t *testing.T) {
+	repo := newRepo()
+	root := storeCommit(t, repo, "root")
+	shared := storeCommit(t, repo, "shared", root)
+	a := storeCommit(t, repo, "a", shared)
+	b := storeCommit(t, repo, "b", shared)
+	c := storeCommit(t, repo, "c", root)
+
+	bases, err := MergeBases(repo, a, b, c)
+	if err != nil {
+		t.Fatalf("Failed to compute merge bases: %v", err)
+	}
+
+	if len(bases) != 1 || bases[0] != root {
+		t.Errorf("Expected merge base [%s], got %v", root, bases)
+	}
+}
+
+func TestMergeBasesUnrelatedHistories(t *testing.T) {
+	repo := newRepo()
+	a := storeCommit(t, repo, "a")
+	b := storeCommit(t, repo, "b")
+
+	bases, err := MergeBases(repo, a, b)
+	if err != nil {
+		t.Fatalf("Failed to compute merge bases: %v", err)
+	}
+
+	if len(bases) != 0 {
+		t.Errorf("Expected no merge base, got %v", bases)
+	}
+}
+
+func TestIsAncestor(t *testing.T) {
+	repo := newRepo()
+	root := storeCommit(t, repo, "root")
+	side := storeCommit(t, repo, "side", root)
+	main := storeCommit(t, repo, "main", root)
+	merge := storeCommit(t, repo, "merge", main, side)
+
+	tests := []struct {
+		ancestor, commit string
+		expected         bool
+	}{
+		{root, merge, true},
+		{side, merge, true},
+		{merge, merge, true},
+		{merge, root, false},
+		{side, main, false},
+	}
+
+	for _, test := range tests {
+		isAncestor, err := IsAncestor(repo, test.ancestor, test.commit)
+		if err != nil {
+			t.Fatalf("Failed to check ancestry: %v", err)
+		}
+		if isAncestor != test.expected {
+			t.Errorf("IsAncestor(%s, %s) = %v, expected %v", test.ancestor[:7], test.commit[:7], isAncestor, test.expected)
+		}
+	}
+}
+
+func TestUnreachable(t *testing.T) {
+	repo := newRepo()
+	root := storeCommit(t, repo, "root")
+	base := storeCommit(t, repo, "base", root)
+	first := storeCommit(t, repo, "first", base)
+	second := storeCommit(t, repo, "second", first)
+
+	lost, err := Unreachable(repo, second, []string{base})
+	if err != nil {
+		t.Fatalf("Failed to find unreachable commits: %v", err)
+	}
+	if len(lost) != 2 || lost[0] != second || lost[1] != first {
+		t.Errorf("Expected [%s %s], got %v", second, first, lost)
+	}
+
+	lost, err = Unreachable(repo, first, []string{second})
+	if err != nil {
+		t.Fatalf("Failed to find unreachable commits: %v", err)
+	}
+	if len(lost) != 0 {
+		t.Errorf("Expected no unreachable commits, got %v", lost)
+	}
+}
@@ -0,0 +1,156 @@
+package identity
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/nexxeln/mini-git/config"
+)
+
+// Identity is the person recorded as the author or committer of a commit,
+// along with when they acted.
+type Identity struct {
+	Name  string
+	Email string
+	When  time.Time
+}
+
+// String formats the identity the way commits record it: "Name <email>".
+func (id Identity) String() string {
+	return fmt.Sprintf("%s <%s>", id.Name, id.Email)
+}
+
+// Author returns the author identity for the repository at repoPath, which
+// may be empty outside a repository. It comes from, in order of precedence,
+// MINI_GIT_AUTHOR_NAME, MINI_GIT_AUTHOR_EMAIL and MINI_GIT_AUTHOR_DATE, the
+// user.name and user.email settings of the repository, global and system
+// config, and finally the login name and host name.
+func Author(repoPath string) (Identity, error) {
+	return lookup(repoPath, "AUTHOR")
+}
+
+// Committer returns the committer identity, resolved like Author but from
+// the MINI_GIT_COMMITTER_* variables.
+func Committer(repoPath string) (Identity, error) {
+	return lookup(repoPath, "COMMITTER")
+}
+
+func lookup(repoPath, role string) (Identity, error) {
+	name, email, err := configured(repoPath)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	if value := os.Getenv("MINI_GIT_" + role + "_NAME"); value != "" {
+		name = value
+	}
+	if value := os.Getenv("MINI_GIT_" + role + "_EMAIL"); value != "" {
+		email = value
+	}
+
+	if name == "" || email == "" {
+		defaultName, defaultEmail := systemIdentity()
+		if name == "" {
+			name = defaultName
+		}
+		if email == "" {
+			email = defaultEmail
+		}
+	}
+
+	if strings.ContainsAny(name, "<>\n") || strings.ContainsAny(email, "<>\n") {
+		return Identity{}, fmt.Errorf("invalid %s identity %q <%s>: names and emails cannot contain '<', '>' or newlines", strings.ToLower(role), name, email)
+	}
+
+	when := time.Now()
+	if value := os.Getenv("MINI_GIT_" + role + "_DATE"); value != "" {
+		when, err = ParseDate(value)
+		if err != nil {
+			return Identity{}, fmt.Errorf("invalid MINI_GIT_%s_DATE: %v", role, err)
+		}
+	}
+
+	return Identity{Name: strings.TrimSpace(name), Email: strings.TrimSpace(email), When: when}, nil
+}
+
+// configured returns user.name and user.email from the layered config.
+func configured(repoPath string) (string, string, error) {
+	c, err := config.LoadAll(repoPath)
+	if err != nil {
+		return "", "", err
+	}
+	name, _ := c.Get("user.name")
+	email, _ := c.Get("user.email")
+	return name, email, nil
+}
+
+// System returns the identity guessed from the login name and host name,
+// acting now. It ignores config and the environment, so it is always valid.
+func System() Identity {
+	name, email := systemIdentity()
+	return Identity{Name: name, Email: email, When: time.Now()}
+}
+
+// systemIdentity guesses an identity from the login name and host name.
+func systemIdentity() (string, string) {
+	login := "unknown"
+	name := ""
+	if u, err := user.Current(); err == nil {
+		login = u.Username
+		name = strings.TrimSpace(strings.Split(u.Name, ",")[0])
+	}
+	if name == "" {
+		name = login
+	}
+
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "localhost"
+	}
+	return name, login + "@" + host
+}
+
+// dateLayouts are the formats ParseDate accepts besides Git's internal
+// "<unix seconds> <zone>" form.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05 -0700",
+	"2006-01-02 15:04:05",
+	time.RFC1123Z,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon Jan 2 15:04:05 2006 -0700",
+}
+
+// ParseDate parses a date given in Git's internal format ("1625140800
+// +0200", optionally with a leading @), as RFC 2822 or as ISO 8601. Dates
+// without a zone are taken to be UTC.
+func ParseDate(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+
+	fields := strings.Fields(strings.TrimPrefix(value, "@"))
+	if len(fields) >= 1 && len(fields) <= 2 {
+		if seconds, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+			when := time.Unix(seconds, 0).UTC()
+			if len(fields) == 2 {
+				zone, err := parseZone(fields[1])
+				if err != nil {
+					return time.Time{}, err
+				}
+				when = when.In(zone)
+			}
+			return when, nil
+		}
+	}
+
+	for _, layout := range dateLayouts {
+		if when, err := time.Parse(layout, value); err == nil {
+			return when, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognized date %q", value)
+}
@@ -0,0 +1,185 @@
+package identity
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func setup(t *testing.T) (string, string) {
+	home := t.TempDir()
+	repo := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("MINI_GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("MINI_GIT_CONFIG_GLOBAL", "")
+	for _, role := range []string{"AUTHOR", "COMMITTER"} {
+		for _, field := range []string{"NAME", "EMAIL", "DATE"} {
+			t.Setenv("MINI_GIT_"+role+"_"+field, "")
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(repo, ".mini-git"), 0755); err != nil {
+		t.Fatalf("Failed to create repository: %v", err)
+	}
+	return home, repo
+}
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", path, err)
+	}
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	home, repo := setup(t)
+	writeFile(t, filepath.Join(home, ".mini-gitconfig"), "[user]\n\tname = Global User\n\temail = global@example.com\n")
+
+	author, err := Author(repo)
+	if err != nil {
+		t.Fatalf("Author failed: %v", err)
+	}
+	if author.String() != "Global User <global@example.com>" {
+		t.Errorf("Expected the global identity, got %q", author)
+	}
+
+	writeFile(t, filepath.Join(repo, ".mini-git", "config"), "[user]\n\tname = Repo User\n")
+	author, err = Author(repo)
+	if err != nil {
+		t.Fatalf("Author failed: %v", err)
+	}
+	if author.String() != "Repo User <global@example.com>" {
+		t.Errorf("Expected the repository name with the global email, got %q", author)
+	}
+}
+
+func TestEnvironmentOverrides(t *testing.T) {
+	_, repo := setup(t)
+	writeFile(t, filepath.Join(repo, ".mini-git", "config"), "[user]\n\tname = Repo User\n\temail = repo@example.com\n")
+	t.Setenv("MINI_GIT_AUTHOR_NAME", "Env Author")
+	t.Setenv("MINI_GIT_AUTHOR_DATE", "1625140800 +0200")
+	t.Setenv("MINI_GIT_COMMITTER_EMAIL", "committer@example.com")
+
+	author, err := Author(repo)
+	if err != nil {
+		t.Fatalf("Author failed: %v", err)
+	}
+	if author.String() != "Env Author <repo@example.com>" {
+		t.Errorf("Unexpected author %q", author)
+	}
+	if author.When.Unix() != 1625140800 {
+		t.Errorf("Expected author date 1625140800, got %d", author.When.Unix())
+	}
+	if _, offset := author.When.Zone(); offset != 2*60*60 {
+		t.Errorf("Expected a +0200 offset, got %d seconds", offset)
+	}
+
+	committer, err := Committer(repo)
+	if err != nil {
+		t.Fatalf("Committer failed: %v", err)
+	}
+	if committer.String() != "Repo User <committer@example.com>" {
+		t.Errorf("Unexpected committer %q", committer)
+	}
+	if time.Since(committer.When) > time.Minute {
+		t.Errorf("Expected the committer date to be now, got %v", committer.When)
+	}
+}
+
+func TestSystemFallback(t *testing.T) {
+	_, repo := setup(t)
+
+	author, err := Author(repo)
+	if err != nil {
+		t.Fatalf("Author failed: %v", err)
+	}
+	if author.Name == "" || !strings.Contains(author.Email, "@") {
+		t.Errorf("Expected a login-based identity, got %q", author)
+	}
+	if system := System(); system.String() != author.String() {
+		t.Errorf("Expected System to match the fallback, got %q and %q", system, author)
+	}
+
+	// Outside a repository only the global config applies.
+	if _, err := Author(""); err != nil {
+		t.Errorf("Author outside a repository failed: %v", err)
+	}
+}
+
+func TestInvalidIdentity(t *testing.T) {
+	_, repo := setup(t)
+	t.Setenv("MINI_GIT_AUTHOR_NAME", "Evil <name>")
+	if _, err := Author(repo); err == nil {
+		t.Error("Expected an error for a name containing angle brackets")
+	}
+
+	t.Setenv("MINI_GIT_AUTHOR_NAME", "")
+	t.Setenv("MINI_GIT_AUTHOR_DATE", "yesterday-ish")
+	if _, err := Author(repo); err == nil {
+		t.Error("Expected an error for an unparseable date")
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		value  string
+		unix   int64
+		offset int
+	}{
+		{"1625140800", 1625140800, 0},
+		{"@1625140800 -0530", 1625140800, -(5*60*60 + 30*60)},
+		{"2021-07-01T14:00:00+02:00", 1625140800, 2 * 60 * 60},
+		{"2021-07-01T12:00:00", 1625140800, 0},
+		{"2021-07-01 14:00:00 +0200", 1625140800, 2 * 60 * 60},
+		{"Thu, 01 Jul 2021 14:00:00 +0200", 1625140800, 2 * 60 * 60},
+		{"Thu Jul 1 14:00:00 2021 +0200", 1625140800, 2 * 60 * 60},
+	}
+	for _, test := range tests {
+		when, err := ParseDate(test.value)
+		if err != nil {
+			t.Errorf("ParseDate(%q) failed: %v", test.value, err)
+			continue
+		}
+		_, offset := when.Zone()
+		if when.Unix() != test.unix || offset != test.offset {
+			t.Errorf("ParseDate(%q) = %d with offset %d; expected %d with offset %d", test.value, when.Unix(), offset, test.unix, test.offset)
+		}
+	}
+
+	for _, value := range []string{"", "tomorrow", "1625140800 +02"} {
+		if _, err := ParseDate(value); err == nil {
+			t.Errorf("ParseDate(%q) should have failed", value)
+		}
+	}
+}
+
+func TestParseSignature(t *testing.T) {
+	tests := []struct {
+		line      string
+		who       string
+		unix      int64
+		offset    int
+		roundTrip bool
+	}{
+		{"John Doe <john@example.com> 1625140800 +0000", "John Doe <john@example.com>", 1625140800, 0, true},
+		{"John Doe <john@example.com> 1625140800 +0200", "John Doe <john@example.com>", 1625140800, 2 * 60 * 60, true},
+		{"John Doe <john@example.com> 1625140800 -0530", "John Doe <john@example.com>", 1625140800, -(5*60*60 + 30*60), true},
+		{"John Doe <john@example.com> 1625140800 bogus", "John Doe <john@example.com>", 1625140800, 0, false},
+	}
+	for _, test := range tests {
+		who, when := ParseSignature(test.line)
+		_, offset := when.Zone()
+		if who != test.who || when.Unix() != test.unix || offset != test.offset {
+			t.Errorf("ParseSignature(%q) = %q, %v; expected %q at %d with offset %d", test.line, who, when, test.who, test.unix, test.offset)
+		}
+		if test.roundTrip {
+			if formatted := who + " " + FormatTime(when); formatted != test.line {
+				t.Errorf("Formatting %q gave %q", test.line, formatted)
+			}
+		}
+	}
+
+	if who, when := ParseSignature("John Doe"); who != "John Doe" || !when.IsZero() {
+		t.Errorf("Expected a line without a time to be returned whole, got %q %v", who, when)
+	}
+}
@@ -0,0 +1,61 @@
+package identity
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FormatTime formats t the way commits, tags and reflogs record it: seconds
+// since the epoch followed by the offset of the zone t is in, such as
+// "1625140800 +0200".
+func FormatTime(t time.Time) string {
+	return fmt.Sprintf("%d %s", t.Unix(), t.Format("-0700"))
+}
+
+// ParseSignature splits a recorded "Name <email> 1625140800 +0200" line into
+// the identity and its time in the recorded zone. A malformed zone is taken
+// to be UTC; a line without a timestamp is returned whole with a zero time.
+func ParseSignature(line string) (string, time.Time) {
+	parts := strings.Split(line, " ")
+	if len(parts) < 3 {
+		return line, time.Time{}
+	}
+
+	timestamp, err := strconv.ParseInt(parts[len(parts)-2], 10, 64)
+	if err != nil {
+		return line, time.Time{}
+	}
+
+	zone, err := parseZone(parts[len(parts)-1])
+	if err != nil {
+		zone = time.UTC
+	}
+	return strings.Join(parts[:len(parts)-2], " "), time.Unix(timestamp, 0).In(zone)
+}
+
+// parseZone returns a fixed zone for a "+hhmm" or "-hhmm" offset. A zero
+// offset gives UTC, so that such times format as "+0000" again.
+func parseZone(offset string) (*time.Location, error) {
+	if len(offset) != 5 || offset[0] != '+' && offset[0] != '-' {
+		return nil, fmt.Errorf("invalid time zone %q", offset)
+	}
+	hours, err := strconv.ParseUint(offset[1:3], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time zone %q", offset)
+	}
+	minutes, err := strconv.ParseUint(offset[3:], 10, 8)
+	if err != nil || minutes >= 60 {
+		return nil, fmt.Errorf("invalid time zone %q", offset)
+	}
+
+	seconds := int(hours)*60*60 + int(minutes)*60
+	if offset[0] == '-' {
+		seconds = -seconds
+	}
+	if seconds == 0 {
+		return time.UTC, nil
+	}
+	return time.FixedZone("", seconds), nil
+}
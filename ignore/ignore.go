@@ -0,0 +1,294 @@
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileName is the name of the per-directory ignore files.
+const FileName = ".mini-gitignore"
+
+// Patterns follow .gitignore rules:
+//
+//   - blank lines and lines starting with # are skipped, and a backslash
+//     escapes a leading # or !, or a trailing space
+//   - a leading ! re-includes paths excluded by earlier patterns, except
+//     inside a directory that is itself excluded
+//   - a trailing / matches directories only
+//   - a pattern containing a slash elsewhere is anchored to the directory
+//     of its ignore file; otherwise it matches a name at any depth
+//   - * and ? do not match slashes, but **/ and /** span directories
+//
+// Deeper ignore files take precedence over shallower ones, which in turn
+// take precedence over .mini-git/info/exclude. Within a file the last
+// matching pattern wins.
+
+type pattern struct {
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+	// basename patterns have no slash and are matched against the last
+	// path component.
+	basename bool
+}
+
+// patternList is the contents of one ignore file.
+type patternList struct {
+	// base is the directory the patterns are relative to, "" for the root.
+	base     string
+	patterns []pattern
+}
+
+// Matcher decides whether work tree paths are ignored. Per-directory ignore
+// files are loaded as their directories are first visited.
+type Matcher struct {
+	repoPath string
+	exclude  *patternList
+	dirs     map[string]*patternList
+	ignored  map[string]bool
+}
+
+// New creates a Matcher for the repository at repoPath.
+func New(repoPath string) (*Matcher, error) {
+	m := &Matcher{
+		repoPath: repoPath,
+		dirs:     make(map[string]*patternList),
+		ignored:  make(map[string]bool),
+	}
+
+	exclude, err := readFile(filepath.Join(repoPath, ".mini-git", "info", "exclude"), "")
+	if err != nil {
+		return nil, err
+	}
+	m.exclude = exclude
+	return m, nil
+}
+
+// Ignored reports whether the slash-separated path, relative to the
+// repository root, is ignored. isDir tells whether it names a directory.
+func (m *Matcher) Ignored(relPath string, isDir bool) (bool, error) {
+	relPath = strings.Trim(relPath, "/")
+	if relPath == "" || relPath == "." {
+		return false, nil
+	}
+
+	// Nothing below an ignored directory can be re-included.
+	if parent := path.Dir(relPath); parent != "." {
+		ignored, err := m.dirIgnored(parent)
+		if err != nil || ignored {
+			return ignored, err
+		}
+	}
+	return m.match(relPath, isDir)
+}
+
+func (m *Matcher) dirIgnored(dir string) (bool, error) {
+	if ignored, seen := m.ignored[dir]; seen {
+		return ignored, nil
+	}
+	ignored, err := m.Ignored(dir, true)
+	if err != nil {
+		return false, err
+	}
+	m.ignored[dir] = ignored
+	return ignored, nil
+}
+
+func (m *Matcher) match(relPath string, isDir bool) (bool, error) {
+	var dirs []string
+	for dir := path.Dir(relPath); ; dir = path.Dir(dir) {
+		if dir == "." {
+			dir = ""
+		}
+		dirs = append(dirs, dir)
+		if dir == "" {
+			break
+		}
+	}
+
+	for _, dir := range dirs {
+		list, err := m.load(dir)
+		if err != nil {
+			return false, err
+		}
+		if ignored, matched := list.match(relPath, isDir); matched {
+			return ignored, nil
+		}
+	}
+
+	ignored, _ := m.exclude.match(relPath, isDir)
+	return ignored, nil
+}
+
+func (m *Matcher) load(dir string) (*patternList, error) {
+	if list, loaded := m.dirs[dir]; loaded {
+		return list, nil
+	}
+	list, err := readFile(filepath.Join(m.repoPath, filepath.FromSlash(dir), FileName), dir)
+	if err != nil {
+		return nil, err
+	}
+	m.dirs[dir] = list
+	return list, nil
+}
+
+// match returns whether relPath is ignored according to the last pattern
+// in the list that matches it, and whether any pattern matched.
+func (l *patternList) match(relPath string, isDir bool) (bool, bool) {
+	rel := relPath
+	if l.base != "" {
+		if !strings.HasPrefix(relPath, l.base+"/") {
+			return false, false
+		}
+		rel = relPath[len(l.base)+1:]
+	}
+
+	for i := len(l.patterns) - 1; i >= 0; i-- {
+		p := l.patterns[i]
+		if p.dirOnly && !isDir {
+			continue
+		}
+		subject := rel
+		if p.basename {
+			subject = path.Base(rel)
+		}
+		if p.re.MatchString(subject) {
+			return !p.negate, true
+		}
+	}
+	return false, false
+}
+
+func readFile(filePath, base string) (*patternList, error) {
+	list := &patternList{base: base}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return list, nil
+		}
+		return nil, fmt.Errorf("failed to read ignore file: %v", err)
+	}
+
+	list.patterns, err = parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filePath, err)
+	}
+	return list, nil
+}
+
+// parse reads the patterns in the contents of an ignore file.
+func parse(data []byte) ([]pattern, error) {
+	var patterns []pattern
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		p, ok, err := parseLine(scanner.Text())
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		if ok {
+			patterns = append(patterns, p)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return patterns, nil
+}
+
+func parseLine(line string) (pattern, bool, error) {
+	line = strings.TrimSuffix(line, "\r")
+
+	// Trailing spaces are dropped unless escaped with a backslash.
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, `\ `) {
+		line = line[:len(line)-1]
+	}
+	if line == "" || strings.HasPrefix(line, "#") {
+		return pattern{}, false, nil
+	}
+
+	var p pattern
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return pattern{}, false, nil
+	}
+
+	if strings.Contains(line, "/") {
+		line = strings.TrimPrefix(line, "/")
+	} else {
+		p.basename = true
+	}
+
+	re, err := compile(line)
+	if err != nil {
+		return pattern{}, false, err
+	}
+	p.re = re
+	return p, true, nil
+}
+
+// compile translates a glob into a regular expression matching whole
+// slash-separated paths.
+func compile(glob string) (*regexp.Regexp, error) {
+	var expr strings.Builder
+	expr.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/") && (i == 0 || glob[i-1] == '/'):
+			// Leading **/ or /**/: zero or more directories.
+			expr.WriteString("(?:.*/)?")
+			i += 2
+		case glob[i:] == "**" && (i == 0 || glob[i-1] == '/'):
+			// Trailing /**: everything inside.
+			expr.WriteString(".*")
+			i++
+		case c == '*':
+			expr.WriteString("[^/]*")
+		case c == '?':
+			expr.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end == 0 {
+				// A ] right after [ is part of the class.
+				if next := strings.IndexByte(glob[i+2:], ']'); next != -1 {
+					end = next + 1
+				} else {
+					end = -1
+				}
+			}
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated character class in %q", glob)
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			expr.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			expr.WriteString(regexp.QuoteMeta(string(glob[i])))
+		default:
+			expr.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	expr.WriteString("$")
+	return regexp.Compile(expr.String())
+}
@@ -0,0 +1,162 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func tempRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	tempDir, err := os.MkdirTemp("", "mini-git-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+
+	for name, content := range files {
+		path := filepath.Join(tempDir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("Failed to create directory: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+	return tempDir
+}
+
+func checkIgnored(t *testing.T, m *Matcher, tests map[string]bool, isDir bool) {
+	t.Helper()
+
+	for path, expected := range tests {
+		ignored, err := m.Ignored(path, isDir)
+		if err != nil {
+			t.Errorf("Ignored(%s) failed: %v", path, err)
+			continue
+		}
+		if ignored != expected {
+			t.Errorf("Ignored(%s, %v) = %v, expected %v", path, isDir, ignored, expected)
+		}
+	}
+}
+
+func TestPatterns(t *testing.T) {
+	repo := tempRepo(t, map[string]string{
+		FileName: `# build outputs
+*.o
+!keep.o
+build/
+/root.txt
+docs/*.html
+**/logs
+cache/**
+a/**/z.txt
+\#hash
+\!bang
+trailing\ 
+spaces   
+file[0-9].txt
+`,
+	})
+
+	m, err := New(repo)
+	if err != nil {
+		t.Fatalf("Failed to create matcher: %v", err)
+	}
+
+	checkIgnored(t, m, map[string]bool{
+		"main.o":           true,
+		"src/deep/util.o":  true,
+		"keep.o":           false,
+		"src/keep.o":       false,
+		"main.c":           false,
+		"build":            false,
+		"src/build":        false,
+		"root.txt":         true,
+		"src/root.txt":     false,
+		"docs/index.html":  true,
+		"docs/api/x.html":  false,
+		"logs":             true,
+		"src/logs":         true,
+		"cache":            false,
+		"cache/item":       true,
+		"cache/a/b/item":   true,
+		"a/z.txt":          true,
+		"a/b/c/z.txt":      true,
+		"b/a/z.txt":        false,
+		"#hash":            true,
+		"!bang":            true,
+		"trailing ":        true,
+		"spaces":           true,
+		"spaces   ":        false,
+		"file7.txt":        true,
+		"fileX.txt":        false,
+		"# build outputs":  false,
+		"build/output.bin": true,
+	}, false)
+
+	checkIgnored(t, m, map[string]bool{
+		"build":     true,
+		"src/build": true,
+		"cache":     false,
+	}, true)
+}
+
+func TestIgnoredDirectoryCannotBeReincluded(t *testing.T) {
+	repo := tempRepo(t, map[string]string{
+		FileName: "out/\n!out/keep.txt\n",
+	})
+
+	m, err := New(repo)
+	if err != nil {
+		t.Fatalf("Failed to create matcher: %v", err)
+	}
+
+	checkIgnored(t, m, map[string]bool{
+		"out/keep.txt":  true,
+		"out/other.txt": true,
+	}, false)
+}
+
+func TestPerDirectoryFilesAndExclude(t *testing.T) {
+	repo := tempRepo(t, map[string]string{
+		".mini-git/info/exclude": "*.swp\n*.tmp\n",
+		FileName:                 "*.log\n",
+		"src/" + FileName:        "!debug.log\n/generated.go\n*.tmp\n!keep.tmp\n",
+	})
+
+	m, err := New(repo)
+	if err != nil {
+		t.Fatalf("Failed to create matcher: %v", err)
+	}
+
+	checkIgnored(t, m, map[string]bool{
+		"app.log":              true,
+		"src/app.log":          true,
+		"src/debug.log":        false,
+		"debug.log":            true,
+		"src/generated.go":     true,
+		"src/pkg/generated.go": false,
+		"generated.go":         false,
+		"notes.swp":            true,
+		"src/notes.swp":        true,
+		"a.tmp":                true,
+		"src/keep.tmp":         false,
+	}, false)
+}
+
+func TestInvalidPattern(t *testing.T) {
+	repo := tempRepo(t, map[string]string{
+		FileName: "file[0-9.txt\n",
+	})
+
+	m, err := New(repo)
+	if err != nil {
+		t.Fatalf("Failed to create matcher: %v", err)
+	}
+	if _, err := m.Ignored("file1.txt", false); err == nil {
+		t.Errorf("Expected an error for an unterminated character class")
+	}
+}
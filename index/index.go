@@ -0,0 +1,325 @@
+package index
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nexxeln/mini-git/internal/lockfile"
+)
+
+// The index is stored in a binary file made of a header ("MIDX", version and
+// entry count), the entries and a trailing SHA-1 of everything before it.
+// Each entry records the stat data of the file it was staged from so that
+// unchanged files can be recognised without reading them:
+//
+//	ctime (int64 ns) mtime (int64 ns) inode (uint64) mode (uint32)
+//	size (uint64) hash (20 bytes) path length (uint16) path
+
+const (
+	signature = "MIDX"
+	version   = 1
+)
+
+// Entry is a staged file.
+type Entry struct {
+	Path  string
+	Hash  string
+	Mode  uint32
+	Size  int64
+	MTime time.Time
+	CTime time.Time
+	Inode uint64
+}
+
+// Index is the list of staged entries, sorted by path with at most one
+// entry per path.
+type Index struct {
+	Entries []Entry
+
+	// written is when the index file was last modified. Files modified at
+	// or after that moment may have changed without their stat data
+	// showing it, so they are never trusted to be unchanged.
+	written time.Time
+}
+
+// NewEntry creates an entry for the file at path with the given blob hash
+// and the stat data in info.
+func NewEntry(path, hash string, info os.FileInfo) Entry {
+	ctime, inode := statDetails(info)
+	return Entry{
+		Path:  path,
+		Hash:  hash,
+		Mode:  fileMode(info),
+		Size:  info.Size(),
+		MTime: info.ModTime(),
+		CTime: ctime,
+		Inode: inode,
+	}
+}
+
+func fileMode(info os.FileInfo) uint32 {
+	if info.Mode()&0111 != 0 {
+		return 0100755
+	}
+	return 0100644
+}
+
+func indexPath(repoPath string) string {
+	return filepath.Join(repoPath, ".mini-git", "index")
+}
+
+// Read loads the index of the repository at repoPath. A missing index is
+// empty, and indexes in the older "<hash> <path>" text format are read
+// without stat data. Should a path appear more than once, as in indexes
+// written by older versions, its last entry wins.
+func Read(repoPath string) (*Index, error) {
+	idx := &Index{}
+
+	path := indexPath(repoPath)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return idx, nil
+		}
+		return nil, fmt.Errorf("failed to read index file: %v", err)
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		idx.written = info.ModTime()
+	}
+
+	if !bytes.HasPrefix(data, []byte(signature)) {
+		idx.Entries, err = parseText(data)
+	} else {
+		idx.Entries, err = parseBinary(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entries := idx.Entries
+	idx.Entries = nil
+	for _, entry := range entries {
+		idx.Add(entry)
+	}
+	return idx, nil
+}
+
+func parseText(data []byte) ([]Entry, error) {
+	var entries []Entry
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid index entry: %s", scanner.Text())
+		}
+		entries = append(entries, Entry{Path: parts[1], Hash: parts[0], Mode: 0100644})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading index file: %v", err)
+	}
+	return entries, nil
+}
+
+func parseBinary(data []byte) ([]Entry, error) {
+	if len(data) < len(signature)+8+sha1.Size {
+		return nil, fmt.Errorf("index file is truncated")
+	}
+
+	body, checksum := data[:len(data)-sha1.Size], data[len(data)-sha1.Size:]
+	if sum := sha1.Sum(body); !bytes.Equal(sum[:], checksum) {
+		return nil, fmt.Errorf("index file is corrupt: checksum mismatch")
+	}
+
+	r := bytes.NewReader(body[len(signature):])
+	var header struct {
+		Version uint32
+		Count   uint32
+	}
+	if err := binary.Read(r, binary.BigEndian, &header); err != nil {
+		return nil, fmt.Errorf("invalid index header: %v", err)
+	}
+	if header.Version != version {
+		return nil, fmt.Errorf("unsupported index version %d", header.Version)
+	}
+
+	entries := make([]Entry, 0, header.Count)
+	for i := uint32(0); i < header.Count; i++ {
+		var fixed struct {
+			CTime   int64
+			MTime   int64
+			Inode   uint64
+			Mode    uint32
+			Size    uint64
+			Hash    [20]byte
+			PathLen uint16
+		}
+		if err := binary.Read(r, binary.BigEndian, &fixed); err != nil {
+			return nil, fmt.Errorf("invalid index entry %d: %v", i, err)
+		}
+		path := make([]byte, fixed.PathLen)
+		if _, err := io.ReadFull(r, path); err != nil {
+			return nil, fmt.Errorf("invalid index entry %d: %v", i, err)
+		}
+
+		entries = append(entries, Entry{
+			Path:  string(path),
+			Hash:  hex.EncodeToString(fixed.Hash[:]),
+			Mode:  fixed.Mode,
+			Size:  int64(fixed.Size),
+			MTime: fromNanos(fixed.MTime),
+			CTime: fromNanos(fixed.CTime),
+			Inode: fixed.Inode,
+		})
+	}
+	if r.Len() != 0 {
+		return nil, fmt.Errorf("index file has %d trailing bytes", r.Len())
+	}
+	return entries, nil
+}
+
+// Write stores the index in the repository at repoPath, replacing the old
+// one through index.lock.
+func (idx *Index) Write(repoPath string) error {
+	var buffer bytes.Buffer
+	buffer.WriteString(signature)
+	binary.Write(&buffer, binary.BigEndian, uint32(version))
+	binary.Write(&buffer, binary.BigEndian, uint32(len(idx.Entries)))
+
+	for _, entry := range idx.Entries {
+		hash, err := hex.DecodeString(entry.Hash)
+		if err != nil || len(hash) != 20 {
+			return fmt.Errorf("invalid hash %q for %s", entry.Hash, entry.Path)
+		}
+		if len(entry.Path) > 0xffff {
+			return fmt.Errorf("path too long: %s", entry.Path)
+		}
+
+		binary.Write(&buffer, binary.BigEndian, toNanos(entry.CTime))
+		binary.Write(&buffer, binary.BigEndian, toNanos(entry.MTime))
+		binary.Write(&buffer, binary.BigEndian, entry.Inode)
+		binary.Write(&buffer, binary.BigEndian, entry.Mode)
+		binary.Write(&buffer, binary.BigEndian, uint64(entry.Size))
+		buffer.Write(hash)
+		binary.Write(&buffer, binary.BigEndian, uint16(len(entry.Path)))
+		buffer.WriteString(entry.Path)
+	}
+
+	sum := sha1.Sum(buffer.Bytes())
+	buffer.Write(sum[:])
+
+	path := indexPath(repoPath)
+	lock, err := lockfile.Lock(path)
+	if err != nil {
+		return err
+	}
+	if _, err := lock.Write(buffer.Bytes()); err != nil {
+		lock.Rollback()
+		return fmt.Errorf("failed to write index file: %v", err)
+	}
+	if err := lock.Commit(); err != nil {
+		return fmt.Errorf("failed to write index file: %v", err)
+	}
+
+	if info, err := os.Stat(path); err == nil {
+		idx.written = info.ModTime()
+	}
+	return nil
+}
+
+// find returns the position of path in the index, or where it would be
+// inserted, and whether it is present.
+func (idx *Index) find(path string) (int, bool) {
+	i := sort.Search(len(idx.Entries), func(i int) bool {
+		return idx.Entries[i].Path >= path
+	})
+	return i, i < len(idx.Entries) && idx.Entries[i].Path == path
+}
+
+// Add stages entry, replacing any existing entry for the same path.
+func (idx *Index) Add(entry Entry) {
+	i, found := idx.find(entry.Path)
+	if found {
+		idx.Entries[i] = entry
+		return
+	}
+	idx.Entries = append(idx.Entries, Entry{})
+	copy(idx.Entries[i+1:], idx.Entries[i:])
+	idx.Entries[i] = entry
+}
+
+// Remove unstages path and reports whether it was staged.
+func (idx *Index) Remove(path string) bool {
+	i, found := idx.find(path)
+	if found {
+		idx.Entries = append(idx.Entries[:i], idx.Entries[i+1:]...)
+	}
+	return found
+}
+
+// Entry returns the entry for path.
+func (idx *Index) Entry(path string) (Entry, bool) {
+	if i, found := idx.find(path); found {
+		return idx.Entries[i], true
+	}
+	return Entry{}, false
+}
+
+// Files returns the staged files as a map of path to blob hash.
+func (idx *Index) Files() map[string]string {
+	files := make(map[string]string)
+	for _, entry := range idx.Entries {
+		files[entry.Path] = entry.Hash
+	}
+	return files
+}
+
+// Unchanged reports whether the file described by info still matches entry
+// according to its stat data, so its content need not be hashed again.
+func (idx *Index) Unchanged(entry Entry, info os.FileInfo) bool {
+	if entry.MTime.IsZero() || !entry.MTime.Before(idx.written) {
+		return false
+	}
+
+	ctime, inode := statDetails(info)
+	return entry.Size == info.Size() &&
+		entry.Mode == fileMode(info) &&
+		entry.MTime.Equal(info.ModTime()) &&
+		entry.CTime.Equal(ctime) &&
+		entry.Inode == inode
+}
+
+// Refresh replaces the stat data recorded for path with info, after the
+// caller has checked that the file's content still matches the entry. It
+// reports whether an entry was updated.
+func (idx *Index) Refresh(path string, info os.FileInfo) bool {
+	i, found := idx.find(path)
+	if found {
+		idx.Entries[i] = NewEntry(path, idx.Entries[i].Hash, info)
+	}
+	return found
+}
+
+func toNanos(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromNanos(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
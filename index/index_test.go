@@ -0,0 +1,269 @@
+package index
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const hash = "0123456789abcdef0123456789abcdef01234567"
+
+func tempRepo(t *testing.T) string {
+	t.Helper()
+
+	tempDir, err := os.MkdirTemp("", "mini-git-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+
+	if err := os.MkdirAll(filepath.Join(tempDir, ".mini-git"), 0755); err != nil {
+		t.Fatalf("Failed to create .mini-git directory: %v", err)
+	}
+	return tempDir
+}
+
+func writeFile(t *testing.T, repo, name, content string) os.FileInfo {
+	t.Helper()
+
+	path := filepath.Join(repo, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Failed to stat %s: %v", name, err)
+	}
+	return info
+}
+
+func TestWriteAndRead(t *testing.T) {
+	repo := tempRepo(t)
+	info := writeFile(t, repo, "file.txt", "content\n")
+
+	idx := &Index{}
+	idx.Add(NewEntry("file.txt", hash, info))
+	idx.Add(Entry{Path: "dir/other.txt", Hash: hash, Mode: 0100755})
+	if err := idx.Write(repo); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+
+	loaded, err := Read(repo)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if len(loaded.Entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(loaded.Entries))
+	}
+
+	entry, _ := loaded.Entry("file.txt")
+	if entry.Path != "file.txt" || entry.Hash != hash || entry.Mode != 0100644 || entry.Size != 8 {
+		t.Errorf("Unexpected entry: %+v", entry)
+	}
+	if !entry.MTime.Equal(info.ModTime()) {
+		t.Errorf("Expected mtime %v, got %v", info.ModTime(), entry.MTime)
+	}
+
+	other, _ := loaded.Entry("dir/other.txt")
+	if other.Path != "dir/other.txt" || other.Mode != 0100755 || !other.MTime.IsZero() {
+		t.Errorf("Unexpected entry: %+v", other)
+	}
+}
+
+func TestReadTextIndex(t *testing.T) {
+	repo := tempRepo(t)
+	text := hash + " a.txt\n" + "1111111111111111111111111111111111111111 a.txt\n"
+	if err := os.WriteFile(filepath.Join(repo, ".mini-git", "index"), []byte(text), 0644); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+
+	idx, err := Read(repo)
+	if err != nil {
+		t.Fatalf("Failed to read text index: %v", err)
+	}
+	if files := idx.Files(); len(files) != 1 || files["a.txt"] != "1111111111111111111111111111111111111111" {
+		t.Errorf("Expected the later entry to win, got %v", files)
+	}
+}
+
+func TestReadMissingIndex(t *testing.T) {
+	idx, err := Read(tempRepo(t))
+	if err != nil || len(idx.Entries) != 0 {
+		t.Errorf("Expected an empty index, got %v (%v)", idx, err)
+	}
+}
+
+func TestReadCorruptIndex(t *testing.T) {
+	repo := tempRepo(t)
+
+	idx := &Index{}
+	idx.Add(Entry{Path: "a.txt", Hash: hash, Mode: 0100644})
+	if err := idx.Write(repo); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+
+	path := filepath.Join(repo, ".mini-git", "index")
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	data[len(data)-25] ^= 0xff
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+
+	if _, err := Read(repo); err == nil {
+		t.Errorf("Expected a checksum error")
+	}
+}
+
+func TestWriteRejectsInvalidHash(t *testing.T) {
+	idx := &Index{}
+	idx.Add(Entry{Path: "a.txt", Hash: "not-a-hash"})
+	if err := idx.Write(tempRepo(t)); err == nil {
+		t.Errorf("Expected an error for an invalid hash")
+	}
+}
+
+func TestUnchanged(t *testing.T) {
+	repo := tempRepo(t)
+	info := writeFile(t, repo, "file.txt", "content\n")
+
+	// Backdate the file so it is not racily clean against the index.
+	past := time.Now().Add(-time.Hour)
+	path := filepath.Join(repo, "file.txt")
+	if err := os.Chtimes(path, past, past); err != nil {
+		t.Fatalf("Failed to set times: %v", err)
+	}
+	info, _ = os.Stat(path)
+
+	idx := &Index{}
+	idx.Add(NewEntry("file.txt", hash, info))
+	if err := idx.Write(repo); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+
+	idx, err := Read(repo)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	entry, _ := idx.Entry("file.txt")
+	if !idx.Unchanged(entry, info) {
+		t.Errorf("Expected untouched file to be unchanged")
+	}
+
+	modified := writeFile(t, repo, "file.txt", "changed content\n")
+	if idx.Unchanged(entry, modified) {
+		t.Errorf("Expected modified file to be detected")
+	}
+
+	if idx.Unchanged(Entry{Path: "file.txt", Hash: hash, Mode: 0100644, Size: 8}, info) {
+		t.Errorf("Expected an entry without stat data never to be unchanged")
+	}
+}
+
+func TestUnchangedRacyEntry(t *testing.T) {
+	repo := tempRepo(t)
+
+	idx := &Index{}
+	if err := idx.Write(repo); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+
+	// A file modified after the index was written may change again within
+	// the same timestamp granularity, so it must not be trusted.
+	time.Sleep(10 * time.Millisecond)
+	info := writeFile(t, repo, "file.txt", "content\n")
+	entry := NewEntry("file.txt", hash, info)
+	if idx.Unchanged(entry, info) {
+		t.Errorf("Expected a racily clean entry not to be trusted")
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	repo := tempRepo(t)
+	info := writeFile(t, repo, "file.txt", "content\n")
+
+	idx := &Index{}
+	idx.Add(Entry{Path: "file.txt", Hash: hash, Mode: 0100644})
+	if !idx.Refresh("file.txt", info) {
+		t.Fatalf("Expected entry to be refreshed")
+	}
+	entry, _ := idx.Entry("file.txt")
+	if entry.Hash != hash || entry.Size != info.Size() || !entry.MTime.Equal(info.ModTime()) {
+		t.Errorf("Unexpected refreshed entry: %+v", entry)
+	}
+
+	if idx.Refresh("missing.txt", info) {
+		t.Errorf("Expected no refresh for a path that is not indexed")
+	}
+}
+
+func TestAddReplacesAndSorts(t *testing.T) {
+	repo := tempRepo(t)
+	other := "1111111111111111111111111111111111111111"
+
+	idx := &Index{}
+	idx.Add(Entry{Path: "b.txt", Hash: hash})
+	idx.Add(Entry{Path: "dir/c.txt", Hash: hash})
+	idx.Add(Entry{Path: "a.txt", Hash: hash})
+	idx.Add(Entry{Path: "b.txt", Hash: other})
+	if err := idx.Write(repo); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+
+	loaded, err := Read(repo)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	expected := []string{"a.txt", "b.txt", "dir/c.txt"}
+	if len(loaded.Entries) != len(expected) {
+		t.Fatalf("Expected %d entries, got %+v", len(expected), loaded.Entries)
+	}
+	for i, path := range expected {
+		if loaded.Entries[i].Path != path {
+			t.Errorf("Expected entry %d to be %s, got %s", i, path, loaded.Entries[i].Path)
+		}
+	}
+	if entry, _ := loaded.Entry("b.txt"); entry.Hash != other {
+		t.Errorf("Expected re-staged entry to replace the old one, got %s", entry.Hash)
+	}
+
+	if !loaded.Remove("a.txt") || loaded.Remove("a.txt") {
+		t.Errorf("Expected a.txt to be removed exactly once")
+	}
+	if _, found := loaded.Entry("a.txt"); found {
+		t.Errorf("Expected a.txt to be gone")
+	}
+}
+
+func TestWriteLocksIndex(t *testing.T) {
+	repo := tempRepo(t)
+	lockPath := filepath.Join(repo, ".mini-git", "index.lock")
+
+	idx := &Index{}
+	idx.Add(Entry{Path: "a.txt", Hash: hash})
+	if err := idx.Write(repo); err != nil {
+		t.Fatalf("Failed to write index: %v", err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock file to be gone after writing")
+	}
+
+	if err := os.WriteFile(lockPath, nil, 0644); err != nil {
+		t.Fatalf("Failed to create lock file: %v", err)
+	}
+	idx.Add(Entry{Path: "b.txt", Hash: hash})
+	if err := idx.Write(repo); err == nil {
+		t.Errorf("Expected writing a locked index to fail")
+	}
+
+	loaded, err := Read(repo)
+	if err != nil {
+		t.Fatalf("Failed to read index: %v", err)
+	}
+	if len(loaded.Entries) != 1 {
+		t.Errorf("Expected the locked index to be left alone, got %+v", loaded.Entries)
+	}
+}
@@ -0,0 +1,56 @@
+// Package lockfile replaces files through a lock file. New contents are
+// written to path.lock, which is created exclusively and then renamed over
+// path, so readers never see a partial file and a concurrent writer fails
+// instead of being lost.
+package lockfile
+
+import (
+	"fmt"
+	"os"
+)
+
+// File is a held lock on a file, and the file's new contents.
+type File struct {
+	path string
+	lock *os.File
+}
+
+// Lock creates path.lock. It fails if the lock already exists.
+func Lock(path string) (*File, error) {
+	lockPath := path + ".lock"
+	lock, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		if os.IsExist(err) {
+			return nil, fmt.Errorf("unable to create %s: another mini-git process seems to be running; remove the file if it is not", lockPath)
+		}
+		return nil, fmt.Errorf("failed to lock %s: %v", path, err)
+	}
+	return &File{path: path, lock: lock}, nil
+}
+
+// Write appends to the new contents.
+func (f *File) Write(p []byte) (int, error) {
+	return f.lock.Write(p)
+}
+
+// Commit replaces the file with what was written and releases the lock. The
+// lock is released even if it fails.
+func (f *File) Commit() error {
+	err := f.lock.Sync()
+	if closeErr := f.lock.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.lock.Name(), f.path)
+	}
+	if err != nil {
+		os.Remove(f.lock.Name())
+	}
+	return err
+}
+
+// Rollback releases the lock and leaves the file as it was.
+func (f *File) Rollback() {
+	f.lock.Close()
+	os.Remove(f.lock.Name())
+}
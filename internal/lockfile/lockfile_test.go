@@ -0,0 +1,61 @@
+package lockfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommitReplacesFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	f, err := Lock(path)
+	if err != nil {
+		t.Fatalf("Failed to lock file: %v", err)
+	}
+	if _, err := Lock(path); err == nil {
+		t.Errorf("Expected locking a locked file to fail")
+	}
+	if _, err := f.Write([]byte("new\n")); err != nil {
+		t.Fatalf("Failed to write lock file: %v", err)
+	}
+	if err := f.Commit(); err != nil {
+		t.Fatalf("Failed to commit lock file: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new\n" {
+		t.Errorf("Expected the new contents, got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock file to be gone after committing")
+	}
+}
+
+func TestRollbackKeepsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatalf("Failed to write file: %v", err)
+	}
+
+	f, err := Lock(path)
+	if err != nil {
+		t.Fatalf("Failed to lock file: %v", err)
+	}
+	f.Write([]byte("new\n"))
+	f.Rollback()
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "old\n" {
+		t.Errorf("Expected the old contents, got %q (%v)", data, err)
+	}
+	if _, err := os.Stat(path + ".lock"); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock file to be gone after rolling back")
+	}
+	if _, err := Lock(path); err != nil {
+		t.Errorf("Expected the file to be lockable again: %v", err)
+	}
+}
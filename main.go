@@ -5,6 +5,8 @@ import (
 	"os"
 
 	"github.com/nexxeln/mini-git/commands"
+	"github.com/nexxeln/mini-git/identity"
+	"github.com/nexxeln/mini-git/repository"
 )
 
 func main() {
@@ -24,17 +26,13 @@ func main() {
 
 	switch command {
 	case "init":
-		if err := commands.Init(cwd); err != nil {
+		if err := commands.Init(cwd, args); err != nil {
 			fmt.Println("Error initializing repository:", err)
 			os.Exit(1)
 		}
 
 	case "add":
-		if len(args) < 1 {
-			fmt.Println("Usage: mini-git add <file>")
-			os.Exit(1)
-		}
-		if err := commands.Add(cwd, args[0]); err != nil {
+		if err := commands.Add(cwd, args); err != nil {
 			fmt.Println("Error adding file:", err)
 			os.Exit(1)
 		}
@@ -44,14 +42,14 @@ func main() {
 			fmt.Println("Usage: mini-git commit <message>")
 			os.Exit(1)
 		}
-		author := "John Doe <john@example.com>" // TODO: Make this configurable
-		if err := commands.Commit(cwd, args[0], author); err != nil {
+		author, committer := identities(cwd)
+		if err := commands.Commit(cwd, args[0], author, committer); err != nil {
 			fmt.Println("Error committing changes:", err)
 			os.Exit(1)
 		}
 
 	case "log":
-		if err := commands.Log(cwd); err != nil {
+		if err := commands.Log(cwd, args); err != nil {
 			fmt.Println("Error displaying log:", err)
 			os.Exit(1)
 		}
@@ -62,26 +60,114 @@ func main() {
 			os.Exit(1)
 		}
 
+	case "diff":
+		if err := commands.Diff(cwd, args); err != nil {
+			fmt.Println("Error displaying diff:", err)
+			os.Exit(1)
+		}
+
 	case "branch":
-		if err := commands.Branch(cwd, args); err != nil {
+		committer := reflogIdentity(cwd)
+		if err := commands.Branch(cwd, args, committer); err != nil {
 			fmt.Println("Error handling branch command:", err)
 			os.Exit(1)
 		}
 
 	case "checkout":
-		if err := commands.Checkout(cwd, args); err != nil {
+		committer := reflogIdentity(cwd)
+		if err := commands.Checkout(cwd, args, committer); err != nil {
 			fmt.Println("Error handling checkout command:", err)
 			os.Exit(1)
 		}
 
 	case "merge":
-		if err := commands.Merge(cwd, args); err != nil {
+		author, committer := identities(cwd)
+		if err := commands.Merge(cwd, args, author, committer); err != nil {
 			fmt.Println("Error handling merge command:", err)
 			os.Exit(1)
 		}
 
+	case "merge-base":
+		if err := commands.MergeBase(cwd, args); err != nil {
+			fmt.Println("Error handling merge-base command:", err)
+			os.Exit(1)
+		}
+
+	case "tag":
+		tagger := func() (identity.Identity, error) {
+			repoRoot, _ := repository.FindRoot(cwd)
+			return identity.Committer(repoRoot)
+		}
+		if err := commands.Tag(cwd, args, tagger); err != nil {
+			fmt.Println("Error handling tag command:", err)
+			os.Exit(1)
+		}
+
+	case "reset":
+		committer := reflogIdentity(cwd)
+		if err := commands.Reset(cwd, args, committer); err != nil {
+			fmt.Println("Error resetting:", err)
+			os.Exit(1)
+		}
+
+	case "rev-parse":
+		if err := commands.RevParse(cwd, args); err != nil {
+			fmt.Println("Error resolving revision:", err)
+			os.Exit(1)
+		}
+
+	case "config":
+		if err := commands.Config(cwd, args); err != nil {
+			fmt.Println("Error handling config command:", err)
+			os.Exit(1)
+		}
+
+	case "cat-file":
+		if err := commands.CatFile(cwd, args); err != nil {
+			fmt.Println("Error reading object:", err)
+			os.Exit(1)
+		}
+
+	case "gc", "repack":
+		if err := commands.Gc(cwd, args); err != nil {
+			fmt.Println("Error packing objects:", err)
+			os.Exit(1)
+		}
+
 	default:
 		fmt.Println("Unknown command:", command)
 		os.Exit(1)
 	}
 }
+
+// identities returns the author and committer for the repository containing
+// cwd, exiting if either is misconfigured. Only commands that always create
+// objects use it.
+func identities(cwd string) (identity.Identity, identity.Identity) {
+	repoRoot, _ := repository.FindRoot(cwd)
+
+	author, err := identity.Author(repoRoot)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	committer, err := identity.Committer(repoRoot)
+	if err != nil {
+		fmt.Println("Error:", err)
+		os.Exit(1)
+	}
+	return author, committer
+}
+
+// reflogIdentity returns the committer that commands which only move refs
+// record in reflogs. A misconfigured identity should not stop them, so it
+// falls back to the login-based one instead of exiting.
+func reflogIdentity(cwd string) identity.Identity {
+	repoRoot, _ := repository.FindRoot(cwd)
+
+	committer, err := identity.Committer(repoRoot)
+	if err != nil {
+		return identity.System()
+	}
+	return committer
+}
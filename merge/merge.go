@@ -0,0 +1,111 @@
+package merge
+
+import (
+	"bytes"
+
+	"github.com/nexxeln/mini-git/diff"
+)
+
+type Result struct {
+	Content   []byte
+	Conflicts int
+}
+
+func ThreeWay(base, ours, theirs []byte, oursLabel, theirsLabel string) *Result {
+	baseLines := diff.SplitLines(base)
+	oursLines := diff.SplitLines(ours)
+	theirsLines := diff.SplitLines(theirs)
+
+	baseToOurs := matchLines(baseLines, oursLines)
+	baseToTheirs := matchLines(baseLines, theirsLines)
+
+	var buffer bytes.Buffer
+	result := &Result{}
+
+	i, j, k := 0, 0, 0
+	for {
+		// Find the next base line that survived unchanged on both sides.
+		stable := -1
+		for n := i; n < len(baseLines); n++ {
+			if baseToOurs[n] >= j && baseToTheirs[n] >= k {
+				stable = n
+				break
+			}
+		}
+
+		if stable == -1 {
+			mergeChunk(&buffer, result, baseLines[i:], oursLines[j:], theirsLines[k:], oursLabel, theirsLabel)
+			break
+		}
+
+		oursEnd, theirsEnd := baseToOurs[stable], baseToTheirs[stable]
+		mergeChunk(&buffer, result, baseLines[i:stable], oursLines[j:oursEnd], theirsLines[k:theirsEnd], oursLabel, theirsLabel)
+		buffer.WriteString(baseLines[stable])
+
+		i, j, k = stable+1, oursEnd+1, theirsEnd+1
+	}
+
+	result.Content = buffer.Bytes()
+	return result
+}
+
+func mergeChunk(buffer *bytes.Buffer, result *Result, base, ours, theirs []string, oursLabel, theirsLabel string) {
+	switch {
+	case linesEqual(ours, theirs):
+		writeLines(buffer, ours)
+	case linesEqual(base, ours):
+		writeLines(buffer, theirs)
+	case linesEqual(base, theirs):
+		writeLines(buffer, ours)
+	default:
+		result.Conflicts++
+		buffer.WriteString("<<<<<<< " + oursLabel + "\n")
+		writeLines(buffer, ours)
+		terminateLine(buffer)
+		buffer.WriteString("=======\n")
+		writeLines(buffer, theirs)
+		terminateLine(buffer)
+		buffer.WriteString(">>>>>>> " + theirsLabel + "\n")
+	}
+}
+
+// matchLines returns, for every line of a, the index of the line it is paired
+// with in b by a diff of the two, or -1 if it has no partner.
+func matchLines(a, b []string) []int {
+	matches := make([]int, len(a))
+	for i := range matches {
+		matches[i] = -1
+	}
+
+	for _, edit := range diff.Compute(a, b, diff.DefaultOptions()) {
+		if edit.Op == diff.OpEqual {
+			matches[edit.OldLine] = edit.NewLine
+		}
+	}
+
+	return matches
+}
+
+func linesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func writeLines(buffer *bytes.Buffer, lines []string) {
+	for _, line := range lines {
+		buffer.WriteString(line)
+	}
+}
+
+func terminateLine(buffer *bytes.Buffer) {
+	if buffer.Len() > 0 && buffer.Bytes()[buffer.Len()-1] != '\n' {
+		buffer.WriteString("\n")
+	}
+}
@@ -0,0 +1,66 @@
+package merge
+
+import (
+	"testing"
+)
+
+func TestThreeWayCleanMerge(t *testing.T) {
+	base := []byte("one\ntwo\nthree\nfour\nfive\n")
+	ours := []byte("ONE\ntwo\nthree\nfour\nfive\n")
+	theirs := []byte("one\ntwo\nthree\nfour\nFIVE\n")
+
+	result := ThreeWay(base, ours, theirs, "ours", "theirs")
+
+	if result.Conflicts != 0 {
+		t.Errorf("Expected no conflicts, got %d", result.Conflicts)
+	}
+
+	expected := "ONE\ntwo\nthree\nfour\nFIVE\n"
+	if string(result.Content) != expected {
+		t.Errorf("Merged content does not match.\nExpected:\n%q\nGot:\n%q", expected, string(result.Content))
+	}
+}
+
+func TestThreeWaySameChangeOnBothSides(t *testing.T) {
+	base := []byte("a\nb\nc\n")
+	changed := []byte("a\nB\nc\n")
+
+	result := ThreeWay(base, changed, changed, "ours", "theirs")
+
+	if result.Conflicts != 0 {
+		t.Errorf("Expected no conflicts, got %d", result.Conflicts)
+	}
+	if string(result.Content) != string(changed) {
+		t.Errorf("Expected %q, got %q", string(changed), string(result.Content))
+	}
+}
+
+func TestThreeWayConflict(t *testing.T) {
+	base := []byte("a\nb\nc\n")
+	ours := []byte("a\nours\nc\n")
+	theirs := []byte("a\ntheirs\nc\n")
+
+	result := ThreeWay(base, ours, theirs, "master", "feature")
+
+	if result.Conflicts != 1 {
+		t.Errorf("Expected 1 conflict, got %d", result.Conflicts)
+	}
+
+	expected := "a\n<<<<<<< master\nours\n=======\ntheirs\n>>>>>>> feature\nc\n"
+	if string(result.Content) != expected {
+		t.Errorf("Merged content does not match.\nExpected:\n%q\nGot:\n%q", expected, string(result.Content))
+	}
+}
+
+func TestThreeWayConflictWithoutTrailingNewline(t *testing.T) {
+	base := []byte("a")
+	ours := []byte("b")
+	theirs := []byte("c")
+
+	result := ThreeWay(base, ours, theirs, "ours", "theirs")
+
+	expected := "<<<<<<< ours\nb\n=======\nc\n>>>>>>> theirs\n"
+	if string(result.Content) != expected {
+		t.Errorf("Merged content does not match.\nExpected:\n%q\nGot:\n%q", expected, string(result.Content))
+	}
+}
@@ -0,0 +1,167 @@
+package objects
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/nexxeln/mini-git/blob"
+	"github.com/nexxeln/mini-git/commit"
+	"github.com/nexxeln/mini-git/tag"
+	"github.com/nexxeln/mini-git/tree"
+)
+
+// Database reads and writes typed objects through an ObjectStore, hashing
+// and encoding them according to the repository's object format.
+type Database struct {
+	Store        ObjectStore
+	Format       Format
+	TreeEncoding tree.Encoding
+}
+
+func NewDatabase(store ObjectStore, format Format, encoding tree.Encoding) *Database {
+	return &Database{
+		Store:        store,
+		Format:       format,
+		TreeEncoding: encoding,
+	}
+}
+
+// NewMemoryDatabase returns a database backed by a fresh MemoryStore.
+func NewMemoryDatabase(format Format, encoding tree.Encoding) *Database {
+	return NewDatabase(NewMemoryStore(), format, encoding)
+}
+
+// Open returns the database of the repository at repoPath, configured from
+// its core.objectformat and core.treeencoding settings.
+func Open(repoPath string) (*Database, error) {
+	format, err := RepositoryFormat(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine object format: %v", err)
+	}
+
+	encoding, err := TreeEncoding(repoPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine tree encoding: %v", err)
+	}
+
+	return NewDatabase(NewFileStore(repoPath), format, encoding), nil
+}
+
+// NewBlob creates a blob hashed according to the database's format.
+func (db *Database) NewBlob(content []byte) (*blob.Blob, error) {
+	if db.Format == FormatGit {
+		return blob.NewGitBlob(content)
+	}
+	return blob.NewBlob(content)
+}
+
+// NewTree creates an empty tree using the database's tree encoding.
+func (db *Database) NewTree() *tree.Tree {
+	t := tree.NewTree()
+	t.Encoding = db.TreeEncoding
+	return t
+}
+
+// Put stores a blob, tree, commit or tag and returns its hash.
+func (db *Database) Put(obj interface{}) (string, error) {
+	var data []byte
+	var hash string
+	var err error
+
+	switch o := obj.(type) {
+	case *blob.Blob:
+		data, err = o.Serialize()
+		hash = o.Hash
+	case *tree.Tree:
+		data, err = o.Serialize()
+		hash = o.Hash()
+	case *commit.Commit:
+		if db.Format == FormatGit {
+			c := *o
+			c.Message = gitMessage(c.Message)
+			o = &c
+		}
+		data, err = o.Serialize()
+		hash = o.Hash()
+	case *tag.Tag:
+		if db.Format == FormatGit {
+			t := *o
+			t.Message = gitMessage(t.Message)
+			o = &t
+		}
+		data, err = o.Serialize()
+		hash = o.Hash()
+	default:
+		return "", fmt.Errorf("unsupported object type")
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize object: %v", err)
+	}
+
+	if db.Format == FormatGit {
+		// Git names every object by the hash of its header and content.
+		sum := sha1.Sum(data)
+		hash = hex.EncodeToString(sum[:])
+	}
+
+	if err := db.Store.Put(hash, data); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
+
+// gitMessage ends a commit or tag message with a newline, as Git does, so
+// that the same commit made by Git and by mini-git gets the same hash.
+func gitMessage(message string) string {
+	if message != "" && !strings.HasSuffix(message, "\n") {
+		message += "\n"
+	}
+	return message
+}
+
+func (db *Database) Blob(hash string) (*blob.Blob, error) {
+	data, err := db.Store.Get(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := blob.Deserialize(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if db.Format == FormatGit {
+		b.Hash = hash
+	}
+	return b, nil
+}
+
+func (db *Database) Tree(hash string) (*tree.Tree, error) {
+	data, err := db.Store.Get(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return tree.DeserializeEncoding(data, db.TreeEncoding)
+}
+
+func (db *Database) Commit(hash string) (*commit.Commit, error) {
+	data, err := db.Store.Get(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return commit.Deserialize(data)
+}
+
+func (db *Database) Tag(hash string) (*tag.Tag, error) {
+	data, err := db.Store.Get(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	return tag.Deserialize(data)
+}
@@ -0,0 +1,195 @@
+package objects
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Deltas use Git's format: the source and target sizes as varints, followed
+// by instructions that either copy a range of the source or insert literal
+// bytes.
+
+const (
+	deltaBlockSize  = 16
+	maxCopySize     = 0x10000
+	maxInsertSize   = 0x7f
+	maxBlockMatches = 64
+)
+
+// createDelta returns a delta that rebuilds target from source.
+func createDelta(source, target []byte) []byte {
+	var delta bytes.Buffer
+	writeDeltaSize(&delta, len(source))
+	writeDeltaSize(&delta, len(target))
+
+	blocks := make(map[string][]int)
+	for i := 0; i+deltaBlockSize <= len(source); i += deltaBlockSize {
+		key := string(source[i : i+deltaBlockSize])
+		if len(blocks[key]) < maxBlockMatches {
+			blocks[key] = append(blocks[key], i)
+		}
+	}
+
+	var pending []byte
+	flush := func() {
+		for len(pending) > 0 {
+			n := len(pending)
+			if n > maxInsertSize {
+				n = maxInsertSize
+			}
+			delta.WriteByte(byte(n))
+			delta.Write(pending[:n])
+			pending = pending[n:]
+		}
+	}
+
+	i := 0
+	for i < len(target) {
+		bestOffset, bestLength := 0, 0
+		if i+deltaBlockSize <= len(target) {
+			for _, offset := range blocks[string(target[i:i+deltaBlockSize])] {
+				length := 0
+				for offset+length < len(source) && i+length < len(target) && source[offset+length] == target[i+length] {
+					length++
+				}
+				if length > bestLength {
+					bestOffset, bestLength = offset, length
+				}
+			}
+		}
+
+		if bestLength < deltaBlockSize {
+			pending = append(pending, target[i])
+			i++
+			continue
+		}
+
+		// Grow the match backwards over bytes that would otherwise be
+		// inserted literally.
+		extended := 0
+		for bestOffset > 0 && len(pending) > 0 && source[bestOffset-1] == pending[len(pending)-1] {
+			bestOffset--
+			bestLength++
+			extended++
+			pending = pending[:len(pending)-1]
+		}
+
+		flush()
+		for copied := 0; copied < bestLength; {
+			n := bestLength - copied
+			if n > maxCopySize {
+				n = maxCopySize
+			}
+			writeCopy(&delta, bestOffset+copied, n)
+			copied += n
+		}
+		i += bestLength - extended
+	}
+	flush()
+
+	return delta.Bytes()
+}
+
+func writeCopy(delta *bytes.Buffer, offset, size int) {
+	var args [7]byte
+	command := byte(0x80)
+	n := 0
+
+	for b := 0; b < 4; b++ {
+		if value := byte(offset >> (8 * b)); value != 0 {
+			command |= 1 << b
+			args[n] = value
+			n++
+		}
+	}
+
+	// A size of 0x10000 is encoded as no size bytes at all.
+	if size != maxCopySize {
+		for b := 0; b < 3; b++ {
+			if value := byte(size >> (8 * b)); value != 0 {
+				command |= 1 << (4 + b)
+				args[n] = value
+				n++
+			}
+		}
+	}
+
+	delta.WriteByte(command)
+	delta.Write(args[:n])
+}
+
+func writeDeltaSize(delta *bytes.Buffer, size int) {
+	var buf [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(buf[:], uint64(size))
+	delta.Write(buf[:n])
+}
+
+// applyDelta rebuilds the target of a delta from its source.
+func applyDelta(source, delta []byte) ([]byte, error) {
+	reader := bytes.NewReader(delta)
+
+	sourceSize, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, fmt.Errorf("invalid delta: %v", err)
+	}
+	if sourceSize != uint64(len(source)) {
+		return nil, fmt.Errorf("invalid delta: source size mismatch")
+	}
+
+	targetSize, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, fmt.Errorf("invalid delta: %v", err)
+	}
+
+	target := make([]byte, 0, targetSize)
+	for reader.Len() > 0 {
+		command, _ := reader.ReadByte()
+
+		if command&0x80 == 0 {
+			if command == 0 {
+				return nil, fmt.Errorf("invalid delta: reserved instruction")
+			}
+			literal := make([]byte, command)
+			if _, err := io.ReadFull(reader, literal); err != nil {
+				return nil, fmt.Errorf("invalid delta: truncated insert")
+			}
+			target = append(target, literal...)
+			continue
+		}
+
+		var offset, size int
+		for b := 0; b < 4; b++ {
+			if command&(1<<b) != 0 {
+				value, err := reader.ReadByte()
+				if err != nil {
+					return nil, fmt.Errorf("invalid delta: truncated copy")
+				}
+				offset |= int(value) << (8 * b)
+			}
+		}
+		for b := 0; b < 3; b++ {
+			if command&(1<<(4+b)) != 0 {
+				value, err := reader.ReadByte()
+				if err != nil {
+					return nil, fmt.Errorf("invalid delta: truncated copy")
+				}
+				size |= int(value) << (8 * b)
+			}
+		}
+		if size == 0 {
+			size = maxCopySize
+		}
+
+		if offset+size > len(source) {
+			return nil, fmt.Errorf("invalid delta: copy out of range")
+		}
+		target = append(target, source[offset:offset+size]...)
+	}
+
+	if uint64(len(target)) != targetSize {
+		return nil, fmt.Errorf("invalid delta: target size mismatch")
+	}
+	return target, nil
+}
@@ -0,0 +1,133 @@
+package objects
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/nexxeln/mini-git/blob"
+	"github.com/nexxeln/mini-git/config"
+	"github.com/nexxeln/mini-git/tree"
+)
+
+// Format selects how objects are hashed and laid out on disk. It is chosen
+// when a repository is initialized and recorded as core.objectformat.
+type Format int
+
+const (
+	// FormatMiniGit hashes blobs by their content alone.
+	FormatMiniGit Format = iota
+	// FormatGit produces object hashes and loose object files that are
+	// byte-identical to Git's.
+	FormatGit
+)
+
+func ParseFormat(name string) (Format, error) {
+	switch name {
+	case "", "mini-git":
+		return FormatMiniGit, nil
+	case "git":
+		return FormatGit, nil
+	}
+	return FormatMiniGit, fmt.Errorf("unknown object format: %s", name)
+}
+
+func (f Format) String() string {
+	if f == FormatGit {
+		return "git"
+	}
+	return "mini-git"
+}
+
+func RepositoryFormat(repoPath string) (Format, error) {
+	c, err := config.Load(filepath.Join(repoPath, ".mini-git", "config"))
+	if err != nil {
+		return FormatMiniGit, err
+	}
+
+	name, _ := c.Get("core.objectformat")
+	return ParseFormat(name)
+}
+
+// TreeEncoding returns how the repository encodes tree objects. Git-format
+// repositories always use Git's binary encoding; others use the encoding named
+// by core.treeencoding, text by default.
+func TreeEncoding(repoPath string) (tree.Encoding, error) {
+	c, err := config.Load(filepath.Join(repoPath, ".mini-git", "config"))
+	if err != nil {
+		return tree.EncodingText, err
+	}
+
+	name, _ := c.Get("core.objectformat")
+	format, err := ParseFormat(name)
+	if err != nil {
+		return tree.EncodingText, err
+	}
+	if format == FormatGit {
+		return tree.EncodingGit, nil
+	}
+
+	name, _ = c.Get("core.treeencoding")
+	return tree.ParseEncoding(name)
+}
+
+// CheckSettingChange returns an error if assigning value to the config
+// variable name in the repository at repoPath, or unsetting it when value is
+// empty, would change how its objects are hashed or encoded. Objects record
+// neither, so core.objectformat and core.treeencoding are fixed once the
+// repository holds any.
+func CheckSettingChange(repoPath, name, value string) error {
+	name = strings.ToLower(name)
+	if name != "core.objectformat" && name != "core.treeencoding" {
+		return nil
+	}
+
+	db, err := Open(repoPath)
+	if err != nil {
+		return err
+	}
+	changed := false
+	if name == "core.objectformat" {
+		format, err := ParseFormat(value)
+		if err != nil {
+			return err
+		}
+		changed = format != db.Format
+	} else {
+		encoding, err := tree.ParseEncoding(value)
+		if err != nil {
+			return err
+		}
+		// Git-format repositories always use the binary encoding.
+		changed = db.Format != FormatGit && encoding != db.TreeEncoding
+	}
+	if !changed {
+		return nil
+	}
+
+	errHasObjects := errors.New("has objects")
+	err = db.Store.Iterate(func(string) error { return errHasObjects })
+	if err == errHasObjects {
+		return fmt.Errorf("cannot change %s: the repository already has objects written with the current setting", name)
+	}
+	return err
+}
+
+// NewBlob creates a blob hashed according to the repository's format.
+func NewBlob(repoPath string, content []byte) (*blob.Blob, error) {
+	db, err := Open(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	return db.NewBlob(content)
+}
+
+// NewTree creates an empty tree using the repository's tree encoding.
+func NewTree(repoPath string) (*tree.Tree, error) {
+	db, err := Open(repoPath)
+	if err != nil {
+		return nil, err
+	}
+	return db.NewTree(), nil
+}
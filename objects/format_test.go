@@ -0,0 +1,39 @@
+package objects
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nexxeln/mini-git/blob"
+)
+
+func TestCheckSettingChange(t *testing.T) {
+	tempDir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(tempDir, ".mini-git", "objects"), 0755); err != nil {
+		t.Fatalf("Failed to create objects directory: %v", err)
+	}
+
+	if err := CheckSettingChange(tempDir, "core.treeEncoding", "binary"); err != nil {
+		t.Errorf("Expected an empty repository to allow changing the tree encoding: %v", err)
+	}
+	if err := CheckSettingChange(tempDir, "core.objectformat", "sha256"); err == nil {
+		t.Errorf("Expected an unknown object format to be rejected")
+	}
+
+	b, _ := blob.NewBlob([]byte("hello\n"))
+	if err := Store(tempDir, b); err != nil {
+		t.Fatalf("Failed to store blob: %v", err)
+	}
+
+	for name, value := range map[string]string{"core.treeencoding": "binary", "core.objectformat": "git"} {
+		if err := CheckSettingChange(tempDir, name, value); err == nil {
+			t.Errorf("Expected changing %s with objects present to fail", name)
+		}
+	}
+	for name, value := range map[string]string{"core.treeencoding": "text", "core.objectformat": "", "user.name": "Jane"} {
+		if err := CheckSettingChange(tempDir, name, value); err != nil {
+			t.Errorf("Expected setting %s to %q to be allowed: %v", name, value, err)
+		}
+	}
+}
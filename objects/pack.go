@@ -0,0 +1,581 @@
+package objects
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Packfiles and their indexes follow Git's version 2 layouts. Blobs may be
+// stored as deltas against a similar blob written earlier in the same pack.
+
+const (
+	packTypeCommit   = 1
+	packTypeTree     = 2
+	packTypeBlob     = 3
+	packTypeTag      = 4
+	packTypeOfsDelta = 6
+	packTypeRefDelta = 7
+
+	// deltaWindow is how many preceding blobs are tried as delta bases.
+	deltaWindow = 10
+	// maxDeltaDepth bounds the length of delta chains so reads stay cheap.
+	maxDeltaDepth = 50
+	// minDeltaSize is the smallest blob worth trying to delta.
+	minDeltaSize = 64
+)
+
+var packTypes = map[Type]int{
+	TypeCommit: packTypeCommit,
+	TypeTree:   packTypeTree,
+	TypeBlob:   packTypeBlob,
+	TypeTag:    packTypeTag,
+}
+
+type PackResult struct {
+	Name    string
+	Objects int
+	Deltas  int
+}
+
+type packObject struct {
+	hash    string
+	kind    Type
+	content []byte
+	offset  int64
+	depth   int
+	crc     uint32
+}
+
+// WritePack writes the given objects into a new packfile and index under
+// objects/pack and returns the pack's name. The objects themselves are left
+// in place.
+func WritePack(repoPath string, hashes []string) (*PackResult, error) {
+	store := NewFileStore(repoPath)
+	var objs []*packObject
+	seen := make(map[string]bool)
+	for _, hash := range hashes {
+		if seen[hash] {
+			continue
+		}
+		seen[hash] = true
+
+		data, err := store.Get(hash)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read object %s: %v", hash, err)
+		}
+		kind, content, err := splitObject(data)
+		if err != nil {
+			return nil, fmt.Errorf("invalid object %s: %v", hash, err)
+		}
+		objs = append(objs, &packObject{hash: hash, kind: kind, content: content})
+	}
+
+	// Similar blobs tend to have similar sizes, so ordering blobs by size
+	// puts good delta bases inside each other's window.
+	sort.SliceStable(objs, func(i, j int) bool {
+		if (objs[i].kind == TypeBlob) != (objs[j].kind == TypeBlob) {
+			return objs[i].kind != TypeBlob
+		}
+		if objs[i].kind == TypeBlob {
+			return len(objs[i].content) > len(objs[j].content)
+		}
+		return objs[i].hash < objs[j].hash
+	})
+
+	var pack bytes.Buffer
+	pack.WriteString("PACK")
+	binary.Write(&pack, binary.BigEndian, uint32(2))
+	binary.Write(&pack, binary.BigEndian, uint32(len(objs)))
+
+	result := &PackResult{Objects: len(objs)}
+	var window []*packObject
+
+	for _, obj := range objs {
+		obj.offset = int64(pack.Len())
+
+		var base *packObject
+		var delta []byte
+		if obj.kind == TypeBlob && len(obj.content) >= minDeltaSize {
+			for _, candidate := range window {
+				if candidate.depth >= maxDeltaDepth {
+					continue
+				}
+				d := createDelta(candidate.content, obj.content)
+				if len(d) < len(obj.content)/2 && (delta == nil || len(d) < len(delta)) {
+					base, delta = candidate, d
+				}
+			}
+		}
+
+		var entry bytes.Buffer
+		payload := obj.content
+		if base != nil {
+			obj.depth = base.depth + 1
+			writePackEntryHeader(&entry, packTypeOfsDelta, len(delta))
+			writeOffsetDelta(&entry, obj.offset-base.offset)
+			payload = delta
+			result.Deltas++
+		} else {
+			writePackEntryHeader(&entry, packTypes[obj.kind], len(obj.content))
+		}
+
+		compressed, err := compress(payload)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compress object %s: %v", obj.hash, err)
+		}
+		entry.Write(compressed)
+
+		obj.crc = crc32.ChecksumIEEE(entry.Bytes())
+		pack.Write(entry.Bytes())
+
+		if obj.kind == TypeBlob {
+			window = append(window, obj)
+			if len(window) > deltaWindow {
+				window = window[1:]
+			}
+		}
+	}
+
+	packSum := sha1.Sum(pack.Bytes())
+	pack.Write(packSum[:])
+	result.Name = "pack-" + hex.EncodeToString(packSum[:])
+
+	packDir := filepath.Join(repoPath, ".mini-git", "objects", "pack")
+	if err := os.MkdirAll(packDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create pack directory: %v", err)
+	}
+
+	// The index is written last: a pack without one is never read.
+	if err := writePackFile(filepath.Join(packDir, result.Name+".pack"), pack.Bytes()); err != nil {
+		return nil, fmt.Errorf("failed to write packfile: %v", err)
+	}
+	if err := writePackFile(filepath.Join(packDir, result.Name+".idx"), buildPackIndex(objs, packSum[:])); err != nil {
+		return nil, fmt.Errorf("failed to write pack index: %v", err)
+	}
+
+	return result, nil
+}
+
+// writePackFile writes a read-only pack or pack index. Their names come from
+// their content, so a file that already exists holds the same data and is
+// kept. A new one is written under a temporary name and renamed into place,
+// so it never appears partly written.
+func writePackFile(path string, data []byte) error {
+	if _, err := os.Stat(path); err == nil {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "tmp_pack_")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(tmp.Name(), 0444)
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
+func writePackEntryHeader(w *bytes.Buffer, packType, size int) {
+	b := byte(packType<<4) | byte(size&0x0f)
+	size >>= 4
+	for size != 0 {
+		w.WriteByte(b | 0x80)
+		b = byte(size & 0x7f)
+		size >>= 7
+	}
+	w.WriteByte(b)
+}
+
+func writeOffsetDelta(w *bytes.Buffer, offset int64) {
+	var buf [10]byte
+	pos := len(buf) - 1
+	buf[pos] = byte(offset & 0x7f)
+	for offset >>= 7; offset != 0; offset >>= 7 {
+		offset--
+		pos--
+		buf[pos] = 0x80 | byte(offset&0x7f)
+	}
+	w.Write(buf[pos:])
+}
+
+func buildPackIndex(objs []*packObject, packSum []byte) []byte {
+	sorted := make([]*packObject, len(objs))
+	copy(sorted, objs)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].hash < sorted[j].hash
+	})
+
+	var idx bytes.Buffer
+	idx.Write([]byte{0xff, 't', 'O', 'c'})
+	binary.Write(&idx, binary.BigEndian, uint32(2))
+
+	var fanout [256]uint32
+	for _, obj := range sorted {
+		first, _ := strconv.ParseUint(obj.hash[:2], 16, 8)
+		for b := int(first); b < 256; b++ {
+			fanout[b]++
+		}
+	}
+	binary.Write(&idx, binary.BigEndian, fanout)
+
+	for _, obj := range sorted {
+		raw, _ := hex.DecodeString(obj.hash)
+		idx.Write(raw)
+	}
+	for _, obj := range sorted {
+		binary.Write(&idx, binary.BigEndian, obj.crc)
+	}
+
+	var largeOffsets []int64
+	for _, obj := range sorted {
+		if obj.offset < 0x80000000 {
+			binary.Write(&idx, binary.BigEndian, uint32(obj.offset))
+			continue
+		}
+		binary.Write(&idx, binary.BigEndian, uint32(0x80000000|len(largeOffsets)))
+		largeOffsets = append(largeOffsets, obj.offset)
+	}
+	for _, offset := range largeOffsets {
+		binary.Write(&idx, binary.BigEndian, uint64(offset))
+	}
+
+	idx.Write(packSum)
+	idxSum := sha1.Sum(idx.Bytes())
+	idx.Write(idxSum[:])
+
+	return idx.Bytes()
+}
+
+type packIndex struct {
+	packPath string
+	offsets  map[string]int64
+}
+
+var packIndexCache = struct {
+	sync.Mutex
+	indexes map[string]*packIndex
+}{indexes: make(map[string]*packIndex)}
+
+func loadPackIndexes(repoPath string) ([]*packIndex, error) {
+	idxPaths, err := filepath.Glob(filepath.Join(repoPath, ".mini-git", "objects", "pack", "pack-*.idx"))
+	if err != nil {
+		return nil, err
+	}
+
+	packIndexCache.Lock()
+	defer packIndexCache.Unlock()
+
+	var indexes []*packIndex
+	for _, idxPath := range idxPaths {
+		idx, cached := packIndexCache.indexes[idxPath]
+		if !cached {
+			idx, err = readPackIndex(idxPath)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read pack index %s: %v", filepath.Base(idxPath), err)
+			}
+			packIndexCache.indexes[idxPath] = idx
+		}
+		indexes = append(indexes, idx)
+	}
+
+	return indexes, nil
+}
+
+func readPackIndex(idxPath string) (*packIndex, error) {
+	data, err := os.ReadFile(idxPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < 8+256*4 || !bytes.Equal(data[:4], []byte{0xff, 't', 'O', 'c'}) || binary.BigEndian.Uint32(data[4:8]) != 2 {
+		return nil, fmt.Errorf("unsupported pack index format")
+	}
+
+	count := int(binary.BigEndian.Uint32(data[8+255*4 : 8+256*4]))
+	hashesStart := 8 + 256*4
+	offsetsStart := hashesStart + count*20 + count*4
+	largeStart := offsetsStart + count*4
+	if len(data) < largeStart+40 {
+		return nil, fmt.Errorf("truncated pack index")
+	}
+
+	idx := &packIndex{
+		packPath: strings.TrimSuffix(idxPath, ".idx") + ".pack",
+		offsets:  make(map[string]int64, count),
+	}
+	for i := 0; i < count; i++ {
+		hash := hex.EncodeToString(data[hashesStart+i*20 : hashesStart+(i+1)*20])
+		offset := int64(binary.BigEndian.Uint32(data[offsetsStart+i*4:]))
+		if offset&0x80000000 != 0 {
+			largeIndex := int(offset & 0x7fffffff)
+			if len(data) < largeStart+(largeIndex+1)*8 {
+				return nil, fmt.Errorf("truncated pack index")
+			}
+			offset = int64(binary.BigEndian.Uint64(data[largeStart+largeIndex*8:]))
+		}
+		idx.offsets[hash] = offset
+	}
+
+	return idx, nil
+}
+
+// readPackedObject looks hash up in the repository's packs and returns the
+// object with its header, as it would be stored loose.
+func readPackedObject(repoPath, hash string) ([]byte, bool, error) {
+	indexes, err := loadPackIndexes(repoPath)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for _, idx := range indexes {
+		offset, exists := idx.offsets[hash]
+		if !exists {
+			continue
+		}
+
+		kind, content, err := readPackEntry(repoPath, idx, offset)
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to read %s from %s: %v", hash, filepath.Base(idx.packPath), err)
+		}
+		header := fmt.Sprintf("%s %d\x00", kind, len(content))
+		return append([]byte(header), content...), true, nil
+	}
+
+	return nil, false, nil
+}
+
+func readPackEntry(repoPath string, idx *packIndex, offset int64) (Type, []byte, error) {
+	f, err := os.Open(idx.packPath)
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return "", nil, err
+	}
+	r := bufio.NewReader(f)
+
+	b, err := r.ReadByte()
+	if err != nil {
+		return "", nil, err
+	}
+	packType := int(b>>4) & 0x07
+	for b&0x80 != 0 {
+		// The inflated size is implied by the zlib stream, so the rest of
+		// the size bytes are skipped.
+		if b, err = r.ReadByte(); err != nil {
+			return "", nil, err
+		}
+	}
+
+	var baseKind Type
+	var baseContent []byte
+	switch packType {
+	case packTypeOfsDelta:
+		b, err := r.ReadByte()
+		if err != nil {
+			return "", nil, err
+		}
+		distance := int64(b & 0x7f)
+		for b&0x80 != 0 {
+			if b, err = r.ReadByte(); err != nil {
+				return "", nil, err
+			}
+			distance = ((distance + 1) << 7) | int64(b&0x7f)
+		}
+		baseKind, baseContent, err = readPackEntry(repoPath, idx, offset-distance)
+		if err != nil {
+			return "", nil, err
+		}
+
+	case packTypeRefDelta:
+		var rawHash [20]byte
+		if _, err := io.ReadFull(r, rawHash[:]); err != nil {
+			return "", nil, err
+		}
+		data, err := NewFileStore(repoPath).Get(hex.EncodeToString(rawHash[:]))
+		if err != nil {
+			return "", nil, err
+		}
+		baseKind, baseContent, err = splitObject(data)
+		if err != nil {
+			return "", nil, err
+		}
+	}
+
+	zr, err := zlib.NewReader(r)
+	if err != nil {
+		return "", nil, err
+	}
+	defer zr.Close()
+	payload, err := io.ReadAll(zr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	if baseContent != nil || packType == packTypeOfsDelta || packType == packTypeRefDelta {
+		content, err := applyDelta(baseContent, payload)
+		return baseKind, content, err
+	}
+
+	for kind, t := range packTypes {
+		if t == packType {
+			return kind, payload, nil
+		}
+	}
+	return "", nil, fmt.Errorf("unknown pack entry type %d", packType)
+}
+
+// PackedObjects lists the hashes of all objects stored in packs.
+func PackedObjects(repoPath string) ([]string, error) {
+	indexes, err := loadPackIndexes(repoPath)
+	if err != nil {
+		return nil, err
+	}
+
+	var hashes []string
+	for _, idx := range indexes {
+		for hash := range idx.offsets {
+			hashes = append(hashes, hash)
+		}
+	}
+	return hashes, nil
+}
+
+// UnpackObjects writes every packed object that is not in keep to loose
+// storage, unless a loose copy already exists, so the packs holding them can
+// be removed without losing them. It returns the number of objects written.
+func UnpackObjects(repoPath string, keep map[string]bool) (int, error) {
+	packed, err := PackedObjects(repoPath)
+	if err != nil {
+		return 0, err
+	}
+
+	store := NewFileStore(repoPath)
+	unpacked := 0
+	for _, hash := range packed {
+		if keep[hash] {
+			continue
+		}
+		objectPath, err := store.objectPath(hash)
+		if err != nil {
+			return unpacked, err
+		}
+		if _, err := os.Stat(objectPath); err == nil {
+			continue
+		}
+
+		data, found, err := readPackedObject(repoPath, hash)
+		if err != nil {
+			return unpacked, err
+		}
+		if !found {
+			continue
+		}
+		if err := store.Put(hash, data); err != nil {
+			return unpacked, err
+		}
+		unpacked++
+	}
+	return unpacked, nil
+}
+
+// RemovePacks deletes every pack except the one named keep.
+func RemovePacks(repoPath, keep string) error {
+	packDir := filepath.Join(repoPath, ".mini-git", "objects", "pack")
+	entries, err := os.ReadDir(packDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	packIndexCache.Lock()
+	defer packIndexCache.Unlock()
+
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, "pack-") || strings.TrimSuffix(strings.TrimSuffix(name, ".idx"), ".pack") == keep {
+			continue
+		}
+		path := filepath.Join(packDir, name)
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		delete(packIndexCache.indexes, path)
+	}
+	return nil
+}
+
+// RemoveLooseObjects deletes the loose copies of the given objects, along
+// with any fan-out directories left empty.
+func RemoveLooseObjects(repoPath string, hashes []string) error {
+	objectsDir := filepath.Join(repoPath, ".mini-git", "objects")
+	dirs := make(map[string]bool)
+
+	for _, hash := range hashes {
+		if len(hash) < 3 {
+			continue
+		}
+		objectPath := filepath.Join(objectsDir, hash[:2], hash[2:])
+		if err := os.Remove(objectPath); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		dirs[filepath.Dir(objectPath)] = true
+	}
+
+	for dir := range dirs {
+		// Fails harmlessly while other objects remain in the directory.
+		os.Remove(dir)
+	}
+	return nil
+}
+
+// splitObject separates a serialized object into its type and content.
+func splitObject(data []byte) (Type, []byte, error) {
+	nullIndex := bytes.IndexByte(data, 0)
+	if nullIndex == -1 {
+		return "", nil, fmt.Errorf("no null byte found")
+	}
+
+	name, size, found := strings.Cut(string(data[:nullIndex]), " ")
+	kind := Type(name)
+	if !found {
+		return "", nil, fmt.Errorf("invalid header")
+	}
+	if _, known := packTypes[kind]; !known {
+		return "", nil, fmt.Errorf("unknown object type %s", kind)
+	}
+
+	content := data[nullIndex+1:]
+	if size != strconv.Itoa(len(content)) {
+		return "", nil, fmt.Errorf("content size mismatch")
+	}
+
+	return kind, content, nil
+}
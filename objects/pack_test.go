@@ -0,0 +1,191 @@
+package objects
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nexxeln/mini-git/blob"
+	"github.com/nexxeln/mini-git/tree"
+)
+
+func TestDeltaRoundTrip(t *testing.T) {
+	source := []byte(strings.Repeat("the quick brown fox jumps over the lazy dog\n", 50))
+	target := append([]byte("a new first line\n"), source[:1000]...)
+	target = append(target, []byte("something in the middle\n")...)
+	target = append(target, source[1200:]...)
+
+	delta := createDelta(source, target)
+	if len(delta) >= len(target)/2 {
+		t.Errorf("Expected a compact delta, got %d bytes for a %d byte target", len(delta), len(target))
+	}
+
+	result, err := applyDelta(source, delta)
+	if err != nil {
+		t.Fatalf("Failed to apply delta: %v", err)
+	}
+	if !bytes.Equal(result, target) {
+		t.Errorf("Delta did not reproduce the target")
+	}
+}
+
+func TestApplyDeltaRejectsWrongSource(t *testing.T) {
+	delta := createDelta([]byte("source content"), []byte("target content"))
+	if _, err := applyDelta([]byte("other"), delta); err == nil {
+		t.Errorf("Expected an error applying a delta to the wrong source")
+	}
+}
+
+func TestWritePackAndRetrieve(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mini-git-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	base := strings.Repeat("line of shared content\n", 100)
+	var blobs []*blob.Blob
+	var hashes []string
+	for i := 0; i < 5; i++ {
+		b, _ := blob.NewBlob([]byte(fmt.Sprintf("%sversion %d\n", base, i)))
+		if err := Store(tempDir, b); err != nil {
+			t.Fatalf("Failed to store blob: %v", err)
+		}
+		blobs = append(blobs, b)
+		hashes = append(hashes, b.Hash)
+	}
+
+	tr := tree.NewTree()
+	tr.AddEntry("file.txt", blobs[0].Hash, tree.EntryTypeBlob)
+	if err := Store(tempDir, tr); err != nil {
+		t.Fatalf("Failed to store tree: %v", err)
+	}
+	hashes = append(hashes, tr.Hash())
+
+	result, err := WritePack(tempDir, hashes)
+	if err != nil {
+		t.Fatalf("Failed to write pack: %v", err)
+	}
+	if result.Objects != len(hashes) {
+		t.Errorf("Expected %d packed objects, got %d", len(hashes), result.Objects)
+	}
+	if result.Deltas == 0 {
+		t.Errorf("Expected similar blobs to be stored as deltas")
+	}
+
+	if err := RemoveLooseObjects(tempDir, hashes); err != nil {
+		t.Fatalf("Failed to remove loose objects: %v", err)
+	}
+
+	for _, b := range blobs {
+		retrieved, err := RetrieveBlob(tempDir, b.Hash)
+		if err != nil {
+			t.Fatalf("Failed to retrieve packed blob: %v", err)
+		}
+		if !bytes.Equal(retrieved.Content, b.Content) {
+			t.Errorf("Packed blob content does not match original")
+		}
+	}
+
+	retrievedTree, err := RetrieveTree(tempDir, tr.Hash())
+	if err != nil {
+		t.Fatalf("Failed to retrieve packed tree: %v", err)
+	}
+	if len(retrievedTree.Entries) != 1 || retrievedTree.Entries[0].Hash != blobs[0].Hash {
+		t.Errorf("Packed tree entries do not match original")
+	}
+
+	packed, err := PackedObjects(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to list packed objects: %v", err)
+	}
+	if len(packed) != len(hashes) {
+		t.Errorf("Expected %d packed objects, got %d", len(hashes), len(packed))
+	}
+}
+
+func TestWritePackTwice(t *testing.T) {
+	tempDir := t.TempDir()
+
+	b, _ := blob.NewBlob([]byte("packed twice\n"))
+	if err := Store(tempDir, b); err != nil {
+		t.Fatalf("Failed to store blob: %v", err)
+	}
+
+	first, err := WritePack(tempDir, []string{b.Hash})
+	if err != nil {
+		t.Fatalf("Failed to write pack: %v", err)
+	}
+	second, err := WritePack(tempDir, []string{b.Hash})
+	if err != nil {
+		t.Fatalf("Failed to write the same pack again: %v", err)
+	}
+	if first.Name != second.Name {
+		t.Errorf("Expected the same pack name, got %s and %s", first.Name, second.Name)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(tempDir, ".mini-git", "objects", "pack"))
+	if err != nil {
+		t.Fatalf("Failed to read pack directory: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Errorf("Expected only the pack and its index, got %d files", len(entries))
+	}
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || info.Mode().Perm() != 0444 {
+			t.Errorf("Expected %s to be read-only, got %v (%v)", entry.Name(), info.Mode(), err)
+		}
+	}
+}
+
+func TestUnpackObjects(t *testing.T) {
+	tempDir := t.TempDir()
+
+	var hashes []string
+	for i := 0; i < 3; i++ {
+		b, _ := blob.NewBlob([]byte(fmt.Sprintf("object %d\n", i)))
+		if err := Store(tempDir, b); err != nil {
+			t.Fatalf("Failed to store blob: %v", err)
+		}
+		hashes = append(hashes, b.Hash)
+	}
+
+	old, err := WritePack(tempDir, hashes)
+	if err != nil {
+		t.Fatalf("Failed to write pack: %v", err)
+	}
+	if err := RemoveLooseObjects(tempDir, hashes); err != nil {
+		t.Fatalf("Failed to remove loose objects: %v", err)
+	}
+
+	// Repack only the first object, as gc does once the others become
+	// unreachable.
+	keep := map[string]bool{hashes[0]: true}
+	result, err := WritePack(tempDir, hashes[:1])
+	if err != nil {
+		t.Fatalf("Failed to write pack: %v", err)
+	}
+	unpacked, err := UnpackObjects(tempDir, keep)
+	if err != nil {
+		t.Fatalf("Failed to unpack objects: %v", err)
+	}
+	if unpacked != 2 {
+		t.Errorf("Expected 2 unpacked objects, got %d", unpacked)
+	}
+	if err := RemovePacks(tempDir, result.Name); err != nil {
+		t.Fatalf("Failed to remove packs: %v", err)
+	}
+	if result.Name == old.Name {
+		t.Fatalf("Expected a different pack")
+	}
+
+	for _, hash := range hashes {
+		if _, err := RetrieveBlob(tempDir, hash); err != nil {
+			t.Errorf("Object %s lost after repacking: %v", hash, err)
+		}
+	}
+}
@@ -1,90 +1,77 @@
 package objects
 
 import (
-	"fmt"
-	"os"
-	"path/filepath"
+	"bytes"
+	"compress/zlib"
+	"io"
 
 	"github.com/nexxeln/mini-git/blob"
 	"github.com/nexxeln/mini-git/commit"
 	"github.com/nexxeln/mini-git/tree"
 )
 
+// Store writes obj to the repository at repoPath. It is shorthand for opening
+// the repository's Database and calling Put.
 func Store(repoPath string, obj interface{}) error {
-	var data []byte
-	var hash string
-	var err error
-
-	switch o := obj.(type) {
-	case *blob.Blob:
-		data, err = o.Serialize()
-		hash = o.Hash
-	case *tree.Tree:
-		data, err = o.Serialize()
-		hash = o.Hash()
-	case *commit.Commit:
-		data, err = o.Serialize()
-		hash = o.Hash()
-	default:
-		return fmt.Errorf("unsupported object type")
-	}
-
+	db, err := Open(repoPath)
 	if err != nil {
-		return fmt.Errorf("failed to serialize object: %v", err)
-	}
-
-	objectsDir := filepath.Join(repoPath, ".mini-git", "objects")
-	if err := os.MkdirAll(objectsDir, 0755); err != nil {
-		return fmt.Errorf("failed to create objects directory: %v", err)
+		return err
 	}
 
-	objectPath := filepath.Join(objectsDir, hash[:2], hash[2:])
-	objectDir := filepath.Dir(objectPath)
-	if err := os.MkdirAll(objectDir, 0755); err != nil {
-		return fmt.Errorf("failed to create object directory: %v", err)
-	}
-
-	if err := os.WriteFile(objectPath, data, 0644); err != nil {
-		return fmt.Errorf("failed to write object to file: %v", err)
-	}
-
-	return nil
+	_, err = db.Put(obj)
+	return err
 }
 
 func RetrieveBlob(repoPath, hash string) (*blob.Blob, error) {
-	data, err := retrieveObject(repoPath, hash)
+	db, err := Open(repoPath)
 	if err != nil {
 		return nil, err
 	}
-
-	return blob.Deserialize(data)
+	return db.Blob(hash)
 }
 
 func RetrieveTree(repoPath, hash string) (*tree.Tree, error) {
-	data, err := retrieveObject(repoPath, hash)
+	db, err := Open(repoPath)
 	if err != nil {
 		return nil, err
 	}
-
-	return tree.Deserialize(data)
+	return db.Tree(hash)
 }
 
 func RetrieveCommit(repoPath, hash string) (*commit.Commit, error) {
-	data, err := retrieveObject(repoPath, hash)
+	db, err := Open(repoPath)
 	if err != nil {
 		return nil, err
 	}
+	return db.Commit(hash)
+}
 
-	return commit.Deserialize(data)
+func hasObjectHeader(data []byte) bool {
+	for _, prefix := range []string{"blob ", "tree ", "commit ", "tag "} {
+		if bytes.HasPrefix(data, []byte(prefix)) {
+			return true
+		}
+	}
+	return false
 }
 
-func retrieveObject(repoPath, hash string) ([]byte, error) {
-	objectPath := filepath.Join(repoPath, ".mini-git", "objects", hash[:2], hash[2:])
+func compress(data []byte) ([]byte, error) {
+	var buffer bytes.Buffer
+	w := zlib.NewWriter(&buffer)
+	if _, err := w.Write(data); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
 
-	data, err := os.ReadFile(objectPath)
+func decompress(data []byte) ([]byte, error) {
+	r, err := zlib.NewReader(bytes.NewReader(data))
 	if err != nil {
-		return nil, fmt.Errorf("failed to read object file: %v", err)
+		return nil, err
 	}
-
-	return data, nil
+	defer r.Close()
+	return io.ReadAll(r)
 }
@@ -2,10 +2,16 @@ package objects
 
 import (
 	"bytes"
+	"compress/zlib"
+	"io"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/nexxeln/mini-git/blob"
+	"github.com/nexxeln/mini-git/commit"
+	"github.com/nexxeln/mini-git/tag"
 	"github.com/nexxeln/mini-git/tree"
 )
 
@@ -99,3 +105,164 @@ func TestRetrieveNonExistentObjects(t *testing.T) {
 		t.Errorf("Expected an error when retrieving non-existent tree, but got nil")
 	}
 }
+
+func TestStoreAndRetrieveGitFormat(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mini-git-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	configPath := filepath.Join(tempDir, ".mini-git", "config")
+	if err := os.MkdirAll(filepath.Dir(configPath), 0755); err != nil {
+		t.Fatalf("Failed to create .mini-git directory: %v", err)
+	}
+	if err := os.WriteFile(configPath, []byte("[core]\n\tobjectformat = git\n"), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+
+	b, err := NewBlob(tempDir, []byte("hello world\n"))
+	if err != nil {
+		t.Fatalf("Failed to create new blob: %v", err)
+	}
+
+	// git hash-object of "hello world\n"
+	expectedHash := "3b18e512dba79e4c8300dd08aeb37f8e728b8dad"
+	if b.Hash != expectedHash {
+		t.Fatalf("Incorrect blob hash. Expected %s, got %s", expectedHash, b.Hash)
+	}
+
+	if err := Store(tempDir, b); err != nil {
+		t.Fatalf("Failed to store blob: %v", err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(tempDir, ".mini-git", "objects", expectedHash[:2], expectedHash[2:]))
+	if err != nil {
+		t.Fatalf("Failed to read loose object: %v", err)
+	}
+	r, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		t.Fatalf("Loose object is not zlib-compressed: %v", err)
+	}
+	inflated, _ := io.ReadAll(r)
+	if string(inflated) != "blob 12\x00hello world\n" {
+		t.Errorf("Unexpected loose object content %q", string(inflated))
+	}
+
+	retrievedBlob, err := RetrieveBlob(tempDir, expectedHash)
+	if err != nil {
+		t.Fatalf("Failed to retrieve blob: %v", err)
+	}
+	if retrievedBlob.Hash != expectedHash {
+		t.Errorf("Retrieved blob hash does not match. Expected %s, got %s", expectedHash, retrievedBlob.Hash)
+	}
+
+	tr, err := NewTree(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to create new tree: %v", err)
+	}
+	tr.AddEntry("hello.txt", b.Hash, tree.EntryTypeBlob)
+	if err := Store(tempDir, tr); err != nil {
+		t.Fatalf("Failed to store tree: %v", err)
+	}
+
+	// git mktree for the same single entry
+	expectedTreeHash := "68aba62e560c0ebc3396e8ae9335232cd93a3f60"
+	if tr.Hash() != expectedTreeHash {
+		t.Fatalf("Incorrect tree hash. Expected %s, got %s", expectedTreeHash, tr.Hash())
+	}
+
+	retrievedTree, err := RetrieveTree(tempDir, expectedTreeHash)
+	if err != nil {
+		t.Fatalf("Failed to retrieve tree: %v", err)
+	}
+	if len(retrievedTree.Entries) != 1 || retrievedTree.Entries[0].Hash != b.Hash {
+		t.Errorf("Retrieved tree does not match original: %+v", retrievedTree.Entries)
+	}
+
+	db, err := Open(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to open database: %v", err)
+	}
+	when := time.Unix(1625140800, 0).In(time.FixedZone("", 2*60*60))
+	c := commit.NewCommit(expectedTreeHash, nil, "John Doe <john@example.com>", "John Doe <john@example.com>", "Initial commit")
+	c.AuthorDate, c.CommitDate = when, when
+	commitHash, err := db.Put(c)
+	if err != nil {
+		t.Fatalf("Failed to store commit: %v", err)
+	}
+
+	// git commit -m "Initial commit" of that tree, dated 1625140800 +0200
+	expectedCommitHash := "39b012f688efbbd2fbf99fefd60d1b4cb0b34c98"
+	if commitHash != expectedCommitHash {
+		t.Errorf("Incorrect commit hash. Expected %s, got %s", expectedCommitHash, commitHash)
+	}
+	if c.Message != "Initial commit" {
+		t.Errorf("Expected storing the commit to leave its message alone, got %q", c.Message)
+	}
+
+	tg := tag.NewTag(expectedCommitHash, "commit", "v1.0", "John Doe <john@example.com>", "Release")
+	tg.TaggerDate = when
+	tagHash, err := db.Put(tg)
+	if err != nil {
+		t.Fatalf("Failed to store tag: %v", err)
+	}
+
+	// git tag -a -m Release v1.0 on that commit
+	expectedTagHash := "099057f1b23eb938d2681329a0027dc5d8226545"
+	if tagHash != expectedTagHash {
+		t.Errorf("Incorrect tag hash. Expected %s, got %s", expectedTagHash, tagHash)
+	}
+}
+
+func TestStoreCompressesObjects(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mini-git-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	content := bytes.Repeat([]byte("Hello, World!\n"), 100)
+	b, _ := blob.NewBlob(content)
+	if err := Store(tempDir, b); err != nil {
+		t.Fatalf("Failed to store blob: %v", err)
+	}
+
+	raw, err := os.ReadFile(filepath.Join(tempDir, ".mini-git", "objects", b.Hash[:2], b.Hash[2:]))
+	if err != nil {
+		t.Fatalf("Failed to read loose object: %v", err)
+	}
+	if len(raw) >= len(content) {
+		t.Errorf("Expected the loose object to be compressed, got %d bytes for %d bytes of content", len(raw), len(content))
+	}
+	if _, err := zlib.NewReader(bytes.NewReader(raw)); err != nil {
+		t.Errorf("Loose object is not zlib-compressed: %v", err)
+	}
+}
+
+func TestRetrieveUncompressedObject(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mini-git-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Objects written by earlier versions are stored uncompressed
+	b, _ := blob.NewBlob([]byte("legacy content"))
+	serialized, _ := b.Serialize()
+	objectPath := filepath.Join(tempDir, ".mini-git", "objects", b.Hash[:2], b.Hash[2:])
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0755); err != nil {
+		t.Fatalf("Failed to create object directory: %v", err)
+	}
+	if err := os.WriteFile(objectPath, serialized, 0644); err != nil {
+		t.Fatalf("Failed to write legacy object: %v", err)
+	}
+
+	retrievedBlob, err := RetrieveBlob(tempDir, b.Hash)
+	if err != nil {
+		t.Fatalf("Failed to retrieve legacy blob: %v", err)
+	}
+	if !bytes.Equal(retrievedBlob.Content, b.Content) {
+		t.Errorf("Retrieved blob content does not match original")
+	}
+}
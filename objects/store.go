@@ -0,0 +1,225 @@
+package objects
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectStore holds serialized objects, header included, keyed by hash. It
+// does not interpret or hash the data it is given.
+type ObjectStore interface {
+	Put(hash string, data []byte) error
+	Get(hash string) ([]byte, error)
+	Has(hash string) (bool, error)
+	// Iterate calls fn with the hash of every stored object, in order,
+	// stopping at the first error.
+	Iterate(fn func(hash string) error) error
+}
+
+// FileStore keeps objects in a repository's .mini-git/objects directory:
+// zlib-compressed loose files under a two-character fan-out, plus any packs.
+type FileStore struct {
+	repoPath string
+}
+
+func NewFileStore(repoPath string) *FileStore {
+	return &FileStore{repoPath: repoPath}
+}
+
+func (s *FileStore) objectPath(hash string) (string, error) {
+	if !isObjectHash(hash) {
+		return "", fmt.Errorf("invalid object hash: %q", hash)
+	}
+	return filepath.Join(s.repoPath, ".mini-git", "objects", hash[:2], hash[2:]), nil
+}
+
+func (s *FileStore) Put(hash string, data []byte) error {
+	objectPath, err := s.objectPath(hash)
+	if err != nil {
+		return err
+	}
+
+	compressed, err := compress(data)
+	if err != nil {
+		return fmt.Errorf("failed to compress object: %v", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(objectPath), 0755); err != nil {
+		return fmt.Errorf("failed to create object directory: %v", err)
+	}
+
+	if err := os.WriteFile(objectPath, compressed, 0644); err != nil {
+		return fmt.Errorf("failed to write object to file: %v", err)
+	}
+
+	return nil
+}
+
+func (s *FileStore) Get(hash string) ([]byte, error) {
+	objectPath, err := s.objectPath(hash)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(objectPath)
+	if os.IsNotExist(err) {
+		packed, found, packErr := readPackedObject(s.repoPath, hash)
+		if packErr != nil {
+			return nil, packErr
+		}
+		if !found {
+			return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, hash)
+		}
+		return packed, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object file: %v", err)
+	}
+
+	// Objects written before compression was introduced start directly
+	// with their header and are read as they are.
+	if !hasObjectHeader(data) {
+		data, err = decompress(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress object: %v", err)
+		}
+	}
+
+	return data, nil
+}
+
+func (s *FileStore) Has(hash string) (bool, error) {
+	objectPath, err := s.objectPath(hash)
+	if err != nil {
+		return false, nil
+	}
+
+	if _, err := os.Stat(objectPath); err == nil {
+		return true, nil
+	} else if !os.IsNotExist(err) {
+		return false, err
+	}
+
+	indexes, err := loadPackIndexes(s.repoPath)
+	if err != nil {
+		return false, err
+	}
+	for _, idx := range indexes {
+		if _, exists := idx.offsets[hash]; exists {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *FileStore) Iterate(fn func(hash string) error) error {
+	hashes := make(map[string]bool)
+
+	objectsDir := filepath.Join(s.repoPath, ".mini-git", "objects")
+	dirs, err := os.ReadDir(objectsDir)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read objects directory: %v", err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() || len(dir.Name()) != 2 {
+			continue
+		}
+		files, err := os.ReadDir(filepath.Join(objectsDir, dir.Name()))
+		if err != nil {
+			return fmt.Errorf("failed to read objects directory: %v", err)
+		}
+		for _, file := range files {
+			if hash := dir.Name() + file.Name(); isObjectHash(hash) {
+				hashes[hash] = true
+			}
+		}
+	}
+
+	packed, err := PackedObjects(s.repoPath)
+	if err != nil {
+		return err
+	}
+	for _, hash := range packed {
+		hashes[hash] = true
+	}
+
+	return iterateSorted(hashes, fn)
+}
+
+// MemoryStore keeps objects in memory. It is safe for concurrent use.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	objects map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{objects: make(map[string][]byte)}
+}
+
+func (s *MemoryStore) Put(hash string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.objects[hash] = append([]byte(nil), data...)
+	return nil
+}
+
+func (s *MemoryStore) Get(hash string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, exists := s.objects[hash]
+	if !exists {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, hash)
+	}
+	return append([]byte(nil), data...), nil
+}
+
+func (s *MemoryStore) Has(hash string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, exists := s.objects[hash]
+	return exists, nil
+}
+
+func (s *MemoryStore) Iterate(fn func(hash string) error) error {
+	s.mu.RLock()
+	hashes := make(map[string]bool, len(s.objects))
+	for hash := range s.objects {
+		hashes[hash] = true
+	}
+	s.mu.RUnlock()
+
+	return iterateSorted(hashes, fn)
+}
+
+func iterateSorted(hashes map[string]bool, fn func(hash string) error) error {
+	sorted := make([]string, 0, len(hashes))
+	for hash := range hashes {
+		sorted = append(sorted, hash)
+	}
+	sort.Strings(sorted)
+
+	for _, hash := range sorted {
+		if err := fn(hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isObjectHash(hash string) bool {
+	if len(hash) != 40 {
+		return false
+	}
+	_, err := hex.DecodeString(hash)
+	return err == nil
+}
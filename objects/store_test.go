@@ -0,0 +1,141 @@
+package objects
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/nexxeln/mini-git/commit"
+	"github.com/nexxeln/mini-git/tree"
+)
+
+func testObjectStore(t *testing.T, store ObjectStore) {
+	t.Helper()
+
+	first := "0123456789abcdef0123456789abcdef01234567"
+	second := "89abcdef0123456789abcdef0123456789abcdef"
+	data := []byte("blob 5\x00hello")
+
+	if err := store.Put(second, data); err != nil {
+		t.Fatalf("Failed to put object: %v", err)
+	}
+	if err := store.Put(first, data); err != nil {
+		t.Fatalf("Failed to put object: %v", err)
+	}
+
+	retrieved, err := store.Get(first)
+	if err != nil {
+		t.Fatalf("Failed to get object: %v", err)
+	}
+	if !bytes.Equal(retrieved, data) {
+		t.Errorf("Retrieved data does not match. Expected %q, got %q", data, retrieved)
+	}
+
+	exists, err := store.Has(first)
+	if err != nil || !exists {
+		t.Errorf("Expected stored object to exist, got %v, %v", exists, err)
+	}
+
+	missing := "fedcba9876543210fedcba9876543210fedcba98"
+	exists, err = store.Has(missing)
+	if err != nil || exists {
+		t.Errorf("Expected missing object not to exist, got %v, %v", exists, err)
+	}
+
+	if _, err := store.Get(missing); !errors.Is(err, ErrObjectNotFound) {
+		t.Errorf("Expected ErrObjectNotFound, got %v", err)
+	}
+
+	var hashes []string
+	err = store.Iterate(func(hash string) error {
+		hashes = append(hashes, hash)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Failed to iterate objects: %v", err)
+	}
+	if len(hashes) != 2 || hashes[0] != first || hashes[1] != second {
+		t.Errorf("Expected [%s %s], got %v", first, second, hashes)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testObjectStore(t, NewMemoryStore())
+}
+
+func TestFileStore(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "mini-git-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	testObjectStore(t, NewFileStore(tempDir))
+}
+
+func TestFileStoreRejectsInvalidHash(t *testing.T) {
+	store := NewFileStore(os.TempDir())
+
+	if _, err := store.Get("ab"); err == nil {
+		t.Errorf("Expected an error for a short hash")
+	}
+	if err := store.Put("../../escape", []byte("blob 0\x00")); err == nil {
+		t.Errorf("Expected an error for a malformed hash")
+	}
+}
+
+func TestMemoryDatabase(t *testing.T) {
+	for _, format := range []Format{FormatMiniGit, FormatGit} {
+		encoding := tree.EncodingText
+		if format == FormatGit {
+			encoding = tree.EncodingGit
+		}
+		db := NewMemoryDatabase(format, encoding)
+
+		b, err := db.NewBlob([]byte("file content\n"))
+		if err != nil {
+			t.Fatalf("Failed to create blob: %v", err)
+		}
+		blobHash, err := db.Put(b)
+		if err != nil {
+			t.Fatalf("Failed to put blob: %v", err)
+		}
+		if blobHash != b.Hash {
+			t.Errorf("%s: expected blob hash %s, got %s", format, b.Hash, blobHash)
+		}
+
+		tr := db.NewTree()
+		tr.AddEntry("file.txt", blobHash, tree.EntryTypeBlob)
+		treeHash, err := db.Put(tr)
+		if err != nil {
+			t.Fatalf("Failed to put tree: %v", err)
+		}
+
+		c := commit.NewCommit(treeHash, nil, "John Doe <john@example.com>", "John Doe <john@example.com>", "Initial commit")
+		commitHash, err := db.Put(c)
+		if err != nil {
+			t.Fatalf("Failed to put commit: %v", err)
+		}
+
+		retrievedCommit, err := db.Commit(commitHash)
+		if err != nil {
+			t.Fatalf("Failed to retrieve commit: %v", err)
+		}
+		retrievedTree, err := db.Tree(retrievedCommit.TreeHash)
+		if err != nil {
+			t.Fatalf("Failed to retrieve tree: %v", err)
+		}
+		if len(retrievedTree.Entries) != 1 || retrievedTree.Entries[0].Hash != blobHash {
+			t.Errorf("%s: tree entries do not match", format)
+		}
+
+		retrievedBlob, err := db.Blob(blobHash)
+		if err != nil {
+			t.Fatalf("Failed to retrieve blob: %v", err)
+		}
+		if !bytes.Equal(retrievedBlob.Content, b.Content) || retrievedBlob.Hash != blobHash {
+			t.Errorf("%s: retrieved blob does not match", format)
+		}
+	}
+}
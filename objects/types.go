@@ -0,0 +1,82 @@
+package objects
+
+import (
+	"fmt"
+
+	"github.com/nexxeln/mini-git/blob"
+	"github.com/nexxeln/mini-git/commit"
+	"github.com/nexxeln/mini-git/tag"
+	"github.com/nexxeln/mini-git/tree"
+)
+
+// Type is the kind of an object, as named in its header.
+type Type string
+
+const (
+	TypeBlob   Type = "blob"
+	TypeTree   Type = "tree"
+	TypeCommit Type = "commit"
+	TypeTag    Type = "tag"
+)
+
+// ReadRaw returns an object's type and its content without the header.
+func (db *Database) ReadRaw(hash string) (Type, []byte, error) {
+	data, err := db.Store.Get(hash)
+	if err != nil {
+		return "", nil, err
+	}
+
+	kind, content, err := splitObject(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid object %s: %v", hash, err)
+	}
+	return kind, content, nil
+}
+
+// Retrieve reads an object without knowing its type in advance. The returned
+// value is a *blob.Blob, *tree.Tree, *commit.Commit or *tag.Tag, matching the
+// type.
+func (db *Database) Retrieve(hash string) (interface{}, Type, error) {
+	data, err := db.Store.Get(hash)
+	if err != nil {
+		return nil, "", err
+	}
+
+	kind, _, err := splitObject(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid object %s: %v", hash, err)
+	}
+
+	var obj interface{}
+	switch kind {
+	case TypeBlob:
+		var b *blob.Blob
+		b, err = blob.Deserialize(data)
+		if err == nil && db.Format == FormatGit {
+			b.Hash = hash
+		}
+		obj = b
+	case TypeTree:
+		obj, err = tree.DeserializeEncoding(data, db.TreeEncoding)
+	case TypeCommit:
+		obj, err = commit.Deserialize(data)
+	case TypeTag:
+		obj, err = tag.Deserialize(data)
+	default:
+		return nil, kind, fmt.Errorf("unsupported object type %s", kind)
+	}
+	if err != nil {
+		return nil, kind, err
+	}
+
+	return obj, kind, nil
+}
+
+// Retrieve reads an object of any type from the repository at repoPath.
+func Retrieve(repoPath, hash string) (interface{}, Type, error) {
+	db, err := Open(repoPath)
+	if err != nil {
+		return nil, "", err
+	}
+	return db.Retrieve(hash)
+}
@@ -0,0 +1,79 @@
+package objects
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/nexxeln/mini-git/blob"
+	"github.com/nexxeln/mini-git/commit"
+	"github.com/nexxeln/mini-git/tag"
+	"github.com/nexxeln/mini-git/tree"
+)
+
+func TestRetrieve(t *testing.T) {
+	db := NewMemoryDatabase(FormatMiniGit, tree.EncodingText)
+
+	b, _ := db.NewBlob([]byte("content\n"))
+	blobHash, _ := db.Put(b)
+
+	tr := db.NewTree()
+	tr.AddEntry("file.txt", blobHash, tree.EntryTypeBlob)
+	treeHash, _ := db.Put(tr)
+
+	c := commit.NewCommit(treeHash, nil, "John Doe <john@example.com>", "John Doe <john@example.com>", "Initial commit")
+	commitHash, _ := db.Put(c)
+
+	obj, kind, err := db.Retrieve(blobHash)
+	if err != nil {
+		t.Fatalf("Failed to retrieve blob: %v", err)
+	}
+	if retrieved, ok := obj.(*blob.Blob); kind != TypeBlob || !ok || !bytes.Equal(retrieved.Content, b.Content) {
+		t.Errorf("Expected the blob back, got %s %v", kind, obj)
+	}
+
+	obj, kind, err = db.Retrieve(treeHash)
+	if err != nil {
+		t.Fatalf("Failed to retrieve tree: %v", err)
+	}
+	if retrieved, ok := obj.(*tree.Tree); kind != TypeTree || !ok || len(retrieved.Entries) != 1 {
+		t.Errorf("Expected the tree back, got %s %v", kind, obj)
+	}
+
+	obj, kind, err = db.Retrieve(commitHash)
+	if err != nil {
+		t.Fatalf("Failed to retrieve commit: %v", err)
+	}
+	if retrieved, ok := obj.(*commit.Commit); kind != TypeCommit || !ok || retrieved.TreeHash != treeHash {
+		t.Errorf("Expected the commit back, got %s %v", kind, obj)
+	}
+
+	tg := tag.NewTag(commitHash, string(TypeCommit), "v1.0", "John Doe <john@example.com>", "Release")
+	tagHash, err := db.Put(tg)
+	if err != nil {
+		t.Fatalf("Failed to put tag: %v", err)
+	}
+
+	obj, kind, err = db.Retrieve(tagHash)
+	if err != nil {
+		t.Fatalf("Failed to retrieve tag: %v", err)
+	}
+	if retrieved, ok := obj.(*tag.Tag); kind != TypeTag || !ok || retrieved.ObjectHash != commitHash || retrieved.Name != "v1.0" {
+		t.Errorf("Expected the tag back, got %s %v", kind, obj)
+	}
+
+	kind, content, err := db.ReadRaw(blobHash)
+	if err != nil {
+		t.Fatalf("Failed to read blob: %v", err)
+	}
+	if kind != TypeBlob || string(content) != "content\n" {
+		t.Errorf("Expected raw blob content, got %s %q", kind, content)
+	}
+}
+
+func TestRetrieveMissingObject(t *testing.T) {
+	db := NewMemoryDatabase(FormatMiniGit, tree.EncodingText)
+
+	if _, _, err := db.Retrieve("0123456789abcdef0123456789abcdef01234567"); err == nil {
+		t.Errorf("Expected an error retrieving a missing object")
+	}
+}
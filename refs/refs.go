@@ -0,0 +1,254 @@
+package refs
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/nexxeln/mini-git/identity"
+)
+
+// ZeroHash stands in for a missing commit in reflog entries.
+const ZeroHash = "0000000000000000000000000000000000000000"
+
+// Read returns the commit a ref such as "refs/heads/master" points at. A
+// missing or empty ref yields an empty hash.
+func Read(repoPath, ref string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(repoPath, ".mini-git", filepath.FromSlash(ref)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read ref %s: %v", ref, err)
+	}
+	return strings.TrimSpace(string(content)), nil
+}
+
+// Exists reports whether a ref file is present, even if it is still empty.
+func Exists(repoPath, ref string) bool {
+	info, err := os.Stat(filepath.Join(repoPath, ".mini-git", filepath.FromSlash(ref)))
+	return err == nil && !info.IsDir()
+}
+
+// Head returns the ref HEAD points at, or an empty ref and the commit hash
+// when HEAD is detached.
+func Head(repoPath string) (ref, hash string, err error) {
+	content, err := os.ReadFile(filepath.Join(repoPath, ".mini-git", "HEAD"))
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read HEAD file: %v", err)
+	}
+
+	head := strings.TrimSpace(string(content))
+	if strings.HasPrefix(head, "ref: ") {
+		ref = strings.TrimPrefix(head, "ref: ")
+		hash, err = Read(repoPath, ref)
+		return ref, hash, err
+	}
+	return "", head, nil
+}
+
+// ValidName reports whether name can be used for a branch or tag: it must
+// not be empty, contain "..", whitespace or any of ~^:?*[\, or start or end
+// with a slash or dot.
+func ValidName(name string) bool {
+	if name == "" || name == "@" || strings.Contains(name, "..") || strings.Contains(name, "@{") {
+		return false
+	}
+	if strings.HasPrefix(name, "-") || strings.HasPrefix(name, "/") || strings.HasSuffix(name, "/") ||
+		strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") {
+		return false
+	}
+	for _, r := range name {
+		if r <= ' ' || r == 0x7f || strings.ContainsRune("~^:?*[\\", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// Write points ref at hash without touching any reflog.
+func Write(repoPath, ref, hash string) error {
+	refPath := filepath.Join(repoPath, ".mini-git", filepath.FromSlash(ref))
+	if err := os.MkdirAll(filepath.Dir(refPath), 0755); err != nil {
+		return fmt.Errorf("failed to create ref directory: %v", err)
+	}
+	if err := os.WriteFile(refPath, []byte(hash), 0644); err != nil {
+		return fmt.Errorf("failed to update ref %s: %v", ref, err)
+	}
+	return nil
+}
+
+// Delete removes ref and its reflog.
+func Delete(repoPath, ref string) error {
+	refPath := filepath.Join(repoPath, ".mini-git", filepath.FromSlash(ref))
+	if err := os.Remove(refPath); err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("ref %s does not exist", ref)
+		}
+		return fmt.Errorf("failed to delete ref %s: %v", ref, err)
+	}
+
+	logPath := filepath.Join(repoPath, ".mini-git", "logs", filepath.FromSlash(ref))
+	if err := os.Remove(logPath); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to delete reflog of %s: %v", ref, err)
+	}
+	return nil
+}
+
+// List returns the refs under prefix, such as "refs/tags/", sorted by name.
+func List(repoPath, prefix string) ([]string, error) {
+	return listFiles(filepath.Join(repoPath, ".mini-git"), prefix)
+}
+
+// ListLogs returns the refs that have a reflog, HEAD included, sorted by
+// name.
+func ListLogs(repoPath string) ([]string, error) {
+	return listFiles(filepath.Join(repoPath, ".mini-git", "logs"), "")
+}
+
+// listFiles returns the slash-separated paths, relative to base, of the
+// files under base/prefix.
+func listFiles(base, prefix string) ([]string, error) {
+	root := filepath.Join(base, filepath.FromSlash(prefix))
+
+	var names []string
+	err := filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(base, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list refs: %v", err)
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// Update points ref at hash and records the change, made by committer, in
+// the ref's reflog, and in HEAD's when HEAD is attached to ref.
+func Update(repoPath, ref, hash string, committer identity.Identity, message string) error {
+	oldHash, err := Read(repoPath, ref)
+	if err != nil {
+		return err
+	}
+
+	if err := Write(repoPath, ref, hash); err != nil {
+		return err
+	}
+
+	if err := AppendLog(repoPath, ref, oldHash, hash, committer, message); err != nil {
+		return err
+	}
+
+	headRef, _, err := Head(repoPath)
+	if err != nil {
+		return err
+	}
+	if headRef == ref {
+		return AppendLog(repoPath, "HEAD", oldHash, hash, committer, message)
+	}
+	return nil
+}
+
+// LogEntry is one line of a reflog.
+type LogEntry struct {
+	OldHash  string
+	NewHash  string
+	Identity string
+	Time     time.Time
+	Message  string
+}
+
+// AppendLog adds an entry to the reflog of ref, which may be "HEAD", stamped
+// with the committer's identity and time. Lines use Git's
+// "<old> <new> <identity> <time> <zone>\t<message>" layout.
+func AppendLog(repoPath, ref, oldHash, newHash string, committer identity.Identity, message string) error {
+	if oldHash == "" {
+		oldHash = ZeroHash
+	}
+	if newHash == "" {
+		newHash = ZeroHash
+	}
+
+	logPath := filepath.Join(repoPath, ".mini-git", "logs", filepath.FromSlash(ref))
+	if err := os.MkdirAll(filepath.Dir(logPath), 0755); err != nil {
+		return fmt.Errorf("failed to create reflog directory: %v", err)
+	}
+
+	f, err := os.OpenFile(logPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open reflog: %v", err)
+	}
+	defer f.Close()
+
+	message = strings.ReplaceAll(message, "\n", " ")
+	if _, err := fmt.Fprintf(f, "%s %s %s %s\t%s\n", oldHash, newHash, committer, identity.FormatTime(committer.When), message); err != nil {
+		return fmt.Errorf("failed to write reflog: %v", err)
+	}
+	return nil
+}
+
+// ReadLog returns the reflog of ref, newest entry first.
+func ReadLog(repoPath, ref string) ([]LogEntry, error) {
+	f, err := os.Open(filepath.Join(repoPath, ".mini-git", "logs", filepath.FromSlash(ref)))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open reflog: %v", err)
+	}
+	defer f.Close()
+
+	var entries []LogEntry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if line == "" {
+			continue
+		}
+
+		header, message, _ := strings.Cut(line, "\t")
+		fields := strings.SplitN(header, " ", 3)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("invalid reflog entry: %s", line)
+		}
+
+		who, when := identity.ParseSignature(fields[2])
+		if when.IsZero() {
+			return nil, fmt.Errorf("invalid reflog timestamp: %s", line)
+		}
+
+		entries = append(entries, LogEntry{
+			OldHash:  fields[0],
+			NewHash:  fields[1],
+			Identity: who,
+			Time:     when,
+			Message:  message,
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read reflog: %v", err)
+	}
+
+	for i, j := 0, len(entries)-1; i < j; i, j = i+1, j-1 {
+		entries[i], entries[j] = entries[j], entries[i]
+	}
+	return entries, nil
+}
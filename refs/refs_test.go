@@ -0,0 +1,202 @@
+package refs
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/nexxeln/mini-git/identity"
+)
+
+var johnDoe = identity.Identity{Name: "John Doe", Email: "john@example.com", When: time.Now()}
+
+func tempRepo(t *testing.T) string {
+	t.Helper()
+
+	tempDir, err := os.MkdirTemp("", "mini-git-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+
+	gitDir := filepath.Join(tempDir, ".mini-git")
+	if err := os.MkdirAll(filepath.Join(gitDir, "refs", "heads"), 0755); err != nil {
+		t.Fatalf("Failed to create refs directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/master\n"), 0644); err != nil {
+		t.Fatalf("Failed to write HEAD: %v", err)
+	}
+	return tempDir
+}
+
+func TestUpdateAndRead(t *testing.T) {
+	repo := tempRepo(t)
+	first := "1111111111111111111111111111111111111111"
+	second := "2222222222222222222222222222222222222222"
+
+	if err := Update(repo, "refs/heads/master", first, johnDoe, "commit (initial): one"); err != nil {
+		t.Fatalf("Failed to update ref: %v", err)
+	}
+	if err := Update(repo, "refs/heads/master", second, johnDoe, "commit: two"); err != nil {
+		t.Fatalf("Failed to update ref: %v", err)
+	}
+
+	hash, err := Read(repo, "refs/heads/master")
+	if err != nil || hash != second {
+		t.Errorf("Expected %s, got %s (%v)", second, hash, err)
+	}
+
+	ref, hash, err := Head(repo)
+	if err != nil || ref != "refs/heads/master" || hash != second {
+		t.Errorf("Unexpected HEAD: %s %s (%v)", ref, hash, err)
+	}
+
+	for _, logRef := range []string{"refs/heads/master", "HEAD"} {
+		entries, err := ReadLog(repo, logRef)
+		if err != nil {
+			t.Fatalf("Failed to read reflog: %v", err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("Expected 2 entries in %s reflog, got %d", logRef, len(entries))
+		}
+		if entries[0].OldHash != first || entries[0].NewHash != second || entries[0].Message != "commit: two" {
+			t.Errorf("Unexpected newest entry: %+v", entries[0])
+		}
+		if entries[1].OldHash != ZeroHash || entries[1].Identity != "John Doe <john@example.com>" {
+			t.Errorf("Unexpected oldest entry: %+v", entries[1])
+		}
+	}
+}
+
+func TestUpdateOtherBranchSkipsHeadLog(t *testing.T) {
+	repo := tempRepo(t)
+
+	if err := Update(repo, "refs/heads/topic", "1111111111111111111111111111111111111111", johnDoe, "branch: Created from HEAD"); err != nil {
+		t.Fatalf("Failed to update ref: %v", err)
+	}
+
+	entries, err := ReadLog(repo, "HEAD")
+	if err != nil {
+		t.Fatalf("Failed to read reflog: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("Expected no HEAD reflog entries, got %d", len(entries))
+	}
+}
+
+func TestReadMissingRef(t *testing.T) {
+	repo := tempRepo(t)
+
+	hash, err := Read(repo, "refs/heads/missing")
+	if err != nil || hash != "" {
+		t.Errorf("Expected an empty hash for a missing ref, got %q (%v)", hash, err)
+	}
+	if Exists(repo, "refs/heads/missing") {
+		t.Errorf("Expected missing ref not to exist")
+	}
+}
+
+func TestValidName(t *testing.T) {
+	for _, name := range []string{"master", "feature/login", "v1.0", "release-2"} {
+		if !ValidName(name) {
+			t.Errorf("Expected %q to be valid", name)
+		}
+	}
+	for _, name := range []string{"", "@", "-x", "a..b", "a b", "a~1", "a^", "a:b", "a@{1}", "/a", "a/", ".a", "a."} {
+		if ValidName(name) {
+			t.Errorf("Expected %q to be invalid", name)
+		}
+	}
+}
+
+func TestListAndDelete(t *testing.T) {
+	repo := tempRepo(t)
+	hash := "1111111111111111111111111111111111111111"
+
+	for _, ref := range []string{"refs/tags/v2", "refs/tags/v1", "refs/tags/release/v3"} {
+		if err := Write(repo, ref, hash); err != nil {
+			t.Fatalf("Failed to write ref: %v", err)
+		}
+	}
+
+	tags, err := List(repo, "refs/tags/")
+	if err != nil {
+		t.Fatalf("Failed to list refs: %v", err)
+	}
+	expected := []string{"refs/tags/release/v3", "refs/tags/v1", "refs/tags/v2"}
+	if len(tags) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, tags)
+	}
+	for i := range expected {
+		if tags[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, tags)
+		}
+	}
+
+	if err := Delete(repo, "refs/tags/v1"); err != nil {
+		t.Fatalf("Failed to delete ref: %v", err)
+	}
+	if Exists(repo, "refs/tags/v1") {
+		t.Errorf("Expected deleted ref to be gone")
+	}
+	if err := Delete(repo, "refs/tags/v1"); err == nil {
+		t.Errorf("Expected an error deleting a missing ref")
+	}
+}
+
+func TestListLogs(t *testing.T) {
+	repo := tempRepo(t)
+	hash := "1111111111111111111111111111111111111111"
+
+	if err := Update(repo, "refs/heads/master", hash, johnDoe, "commit (initial): one"); err != nil {
+		t.Fatalf("Failed to update ref: %v", err)
+	}
+	if err := Update(repo, "refs/heads/feature/x", hash, johnDoe, "branch: Created from master"); err != nil {
+		t.Fatalf("Failed to update ref: %v", err)
+	}
+
+	logs, err := ListLogs(repo)
+	if err != nil {
+		t.Fatalf("Failed to list reflogs: %v", err)
+	}
+	expected := []string{"HEAD", "refs/heads/feature/x", "refs/heads/master"}
+	if len(logs) != len(expected) {
+		t.Fatalf("Expected %v, got %v", expected, logs)
+	}
+	for i := range expected {
+		if logs[i] != expected[i] {
+			t.Errorf("Expected %v, got %v", expected, logs)
+		}
+	}
+}
+
+func TestLogRecordsZone(t *testing.T) {
+	repo := tempRepo(t)
+	committer := johnDoe
+	committer.When = time.Unix(1625140800, 0).In(time.FixedZone("", -(5*60*60 + 30*60)))
+
+	if err := Update(repo, "refs/heads/master", "1111111111111111111111111111111111111111", committer, "commit (initial): one"); err != nil {
+		t.Fatalf("Failed to update ref: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(repo, ".mini-git", "logs", "refs", "heads", "master"))
+	if err != nil {
+		t.Fatalf("Failed to read reflog: %v", err)
+	}
+	expected := ZeroHash + " 1111111111111111111111111111111111111111 John Doe <john@example.com> 1625140800 -0530\tcommit (initial): one\n"
+	if string(data) != expected {
+		t.Errorf("Unexpected reflog line:\n%q\nexpected:\n%q", data, expected)
+	}
+
+	entries, err := ReadLog(repo, "refs/heads/master")
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Failed to read reflog: %v", err)
+	}
+	if !entries[0].Time.Equal(committer.When) || entries[0].Identity != "John Doe <john@example.com>" {
+		t.Errorf("Unexpected entry: %+v", entries[0])
+	}
+	if _, offset := entries[0].Time.Zone(); offset != -(5*60*60 + 30*60) {
+		t.Errorf("Expected a -0530 offset, got %d seconds", offset)
+	}
+}
@@ -0,0 +1,268 @@
+package revision
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nexxeln/mini-git/objects"
+	"github.com/nexxeln/mini-git/refs"
+)
+
+// minPrefixLength is the shortest abbreviated hash that is looked up.
+const minPrefixLength = 4
+
+// Resolve turns a revision expression into the full hash of the object it
+// names. An expression starts with HEAD, a branch or other ref, a full or
+// unique abbreviated hash, or a reflog entry such as master@{2}, and may be
+// followed by any number of ~<n> (nth first-parent ancestor) and ^<n> (nth
+// parent) steps. Annotated tags are peeled to their commit before a step.
+func Resolve(repoPath string, db *objects.Database, expr string) (string, error) {
+	base, steps := splitSteps(expr)
+	hash, err := resolveBase(repoPath, db, base)
+	if err != nil {
+		return "", err
+	}
+
+	for steps != "" {
+		op := steps[0]
+		steps = steps[1:]
+
+		hash, _, err = peel(db, hash)
+		if err != nil {
+			return "", err
+		}
+
+		digits := len(steps) - len(strings.TrimLeft(steps, "0123456789"))
+		n := 1
+		if digits > 0 {
+			n, err = strconv.Atoi(steps[:digits])
+			if err != nil {
+				return "", fmt.Errorf("invalid revision %s", expr)
+			}
+			steps = steps[digits:]
+		}
+
+		switch op {
+		case '~':
+			for i := 0; i < n; i++ {
+				c, err := db.Commit(hash)
+				if err != nil {
+					return "", fmt.Errorf("%s: %s is not a commit", expr, hash)
+				}
+				if len(c.ParentHashes) == 0 {
+					return "", fmt.Errorf("%s: history is not that deep", expr)
+				}
+				hash = c.ParentHashes[0]
+			}
+		case '^':
+			c, err := db.Commit(hash)
+			if err != nil {
+				return "", fmt.Errorf("%s: %s is not a commit", expr, hash)
+			}
+			if n == 0 {
+				continue
+			}
+			if n > len(c.ParentHashes) {
+				return "", fmt.Errorf("%s: commit %s has %d parent(s)", expr, hash[:7], len(c.ParentHashes))
+			}
+			hash = c.ParentHashes[n-1]
+		default:
+			return "", fmt.Errorf("invalid revision %s", expr)
+		}
+	}
+
+	return hash, nil
+}
+
+// ResolveCommit resolves expr, peeling annotated tags, and checks that it
+// names a commit.
+func ResolveCommit(repoPath string, db *objects.Database, expr string) (string, error) {
+	hash, err := Resolve(repoPath, db, expr)
+	if err != nil {
+		return "", err
+	}
+
+	hash, kind, err := peel(db, hash)
+	if err != nil {
+		return "", err
+	}
+	if kind != objects.TypeCommit {
+		return "", fmt.Errorf("%s is a %s, not a commit", expr, kind)
+	}
+	return hash, nil
+}
+
+// peel follows annotated tags to the object they point at.
+func peel(db *objects.Database, hash string) (string, objects.Type, error) {
+	for {
+		kind, _, err := db.ReadRaw(hash)
+		if err != nil {
+			return "", "", err
+		}
+		if kind != objects.TypeTag {
+			return hash, kind, nil
+		}
+
+		t, err := db.Tag(hash)
+		if err != nil {
+			return "", "", err
+		}
+		hash = t.ObjectHash
+	}
+}
+
+// splitSteps separates the ~ and ^ steps from the start of an expression.
+// Reflog selectors are kept whole since their braces may hold anything.
+func splitSteps(expr string) (string, string) {
+	start := 0
+	if at := strings.Index(expr, "@{"); at != -1 {
+		if end := strings.IndexByte(expr[at:], '}'); end != -1 {
+			start = at + end + 1
+		}
+	}
+
+	if i := strings.IndexAny(expr[start:], "~^"); i != -1 {
+		return expr[:start+i], expr[start+i:]
+	}
+	return expr, ""
+}
+
+func resolveBase(repoPath string, db *objects.Database, name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("empty revision")
+	}
+
+	if at := strings.Index(name, "@{"); at != -1 && strings.HasSuffix(name, "}") {
+		return resolveReflog(repoPath, name[:at], name[at+2:len(name)-1])
+	}
+
+	if name == "HEAD" || name == "@" {
+		_, hash, err := refs.Head(repoPath)
+		if err != nil {
+			return "", err
+		}
+		if hash == "" {
+			return "", fmt.Errorf("HEAD does not point to a commit yet")
+		}
+		return hash, nil
+	}
+
+	if ref, found := findRef(repoPath, name); found {
+		hash, err := refs.Read(repoPath, ref)
+		if err != nil {
+			return "", err
+		}
+		if hash == "" {
+			return "", fmt.Errorf("%s does not point to a commit yet", name)
+		}
+		return hash, nil
+	}
+
+	if isHexPrefix(name) {
+		return resolvePrefix(db, strings.ToLower(name))
+	}
+
+	return "", fmt.Errorf("unknown revision %s", name)
+}
+
+// findRef looks name up the way Git does: as given, then under refs/,
+// refs/tags/ and refs/heads/.
+func findRef(repoPath, name string) (string, bool) {
+	for _, ref := range []string{name, "refs/" + name, "refs/tags/" + name, "refs/heads/" + name} {
+		if strings.HasPrefix(ref, "refs/") && refs.Exists(repoPath, ref) {
+			return ref, true
+		}
+	}
+	return "", false
+}
+
+func resolveReflog(repoPath, name, selector string) (string, error) {
+	n, err := strconv.Atoi(selector)
+	if err != nil || n < 0 {
+		return "", fmt.Errorf("unsupported reflog selector @{%s}", selector)
+	}
+
+	ref := "HEAD"
+	switch name {
+	case "", "@":
+		// A bare @{n} refers to the current branch.
+		headRef, _, err := refs.Head(repoPath)
+		if err != nil {
+			return "", err
+		}
+		if headRef != "" {
+			ref = headRef
+		}
+	case "HEAD":
+	default:
+		var found bool
+		ref, found = findRef(repoPath, name)
+		if !found {
+			return "", fmt.Errorf("unknown revision %s", name)
+		}
+	}
+
+	entries, err := refs.ReadLog(repoPath, ref)
+	if err != nil {
+		return "", err
+	}
+	if n >= len(entries) {
+		return "", fmt.Errorf("log for %s only has %d entries", ref, len(entries))
+	}
+	if entries[n].NewHash == refs.ZeroHash {
+		return "", fmt.Errorf("%s@{%d} does not point to a commit", name, n)
+	}
+	return entries[n].NewHash, nil
+}
+
+func resolvePrefix(db *objects.Database, prefix string) (string, error) {
+	if len(prefix) == 40 {
+		if exists, err := db.Store.Has(prefix); err != nil {
+			return "", err
+		} else if exists {
+			return prefix, nil
+		}
+		return "", fmt.Errorf("unknown revision %s", prefix)
+	}
+
+	var matches []string
+	err := db.Store.Iterate(func(hash string) error {
+		if strings.HasPrefix(hash, prefix) {
+			matches = append(matches, hash)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("unknown revision %s", prefix)
+	case 1:
+		return matches[0], nil
+	}
+
+	candidates := make([]string, len(matches))
+	for i, hash := range matches {
+		kind, _, err := db.ReadRaw(hash)
+		if err != nil {
+			return "", err
+		}
+		candidates[i] = fmt.Sprintf("%s %s", hash, kind)
+	}
+	return "", fmt.Errorf("short hash %s is ambiguous; candidates are:\n  %s", prefix, strings.Join(candidates, "\n  "))
+}
+
+func isHexPrefix(name string) bool {
+	if len(name) < minPrefixLength || len(name) > 40 {
+		return false
+	}
+	for _, r := range strings.ToLower(name) {
+		if !strings.ContainsRune("0123456789abcdef", r) {
+			return false
+		}
+	}
+	return true
+}
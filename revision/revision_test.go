@@ -0,0 +1,216 @@
+package revision
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nexxeln/mini-git/commit"
+	"github.com/nexxeln/mini-git/identity"
+	"github.com/nexxeln/mini-git/objects"
+	"github.com/nexxeln/mini-git/refs"
+	"github.com/nexxeln/mini-git/tag"
+)
+
+var clock = time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)
+
+func tempRepo(t *testing.T) (string, *objects.Database) {
+	t.Helper()
+
+	tempDir, err := os.MkdirTemp("", "mini-git-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+
+	gitDir := filepath.Join(tempDir, ".mini-git")
+	if err := os.MkdirAll(filepath.Join(gitDir, "refs", "heads"), 0755); err != nil {
+		t.Fatalf("Failed to create refs directory: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(gitDir, "HEAD"), []byte("ref: refs/heads/master\n"), 0644); err != nil {
+		t.Fatalf("Failed to write HEAD: %v", err)
+	}
+
+	db, err := objects.Open(tempDir)
+	if err != nil {
+		t.Fatalf("Failed to open object database: %v", err)
+	}
+	return tempDir, db
+}
+
+func storeCommit(t *testing.T, db *objects.Database, message string, parents ...string) string {
+	t.Helper()
+
+	clock = clock.Add(time.Minute)
+	c := commit.NewCommit("0123456789abcdef0123456789abcdef01234567", parents, "John Doe <john@example.com>", "John Doe <john@example.com>", message)
+	c.AuthorDate = clock
+	c.CommitDate = clock
+
+	hash, err := db.Put(c)
+	if err != nil {
+		t.Fatalf("Failed to store commit: %v", err)
+	}
+	return hash
+}
+
+func setBranch(t *testing.T, repo, branch, hash string) {
+	t.Helper()
+
+	if err := refs.Update(repo, "refs/heads/"+branch, hash, identity.Identity{Name: "John Doe", Email: "john@example.com", When: time.Now()}, "test"); err != nil {
+		t.Fatalf("Failed to update branch: %v", err)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	repo, db := tempRepo(t)
+	root := storeCommit(t, db, "root")
+	side := storeCommit(t, db, "side", root)
+	main := storeCommit(t, db, "main", root)
+	merge := storeCommit(t, db, "merge", main, side)
+
+	setBranch(t, repo, "master", main)
+	setBranch(t, repo, "master", merge)
+	setBranch(t, repo, "topic", side)
+
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"HEAD", merge},
+		{"@", merge},
+		{"master", merge},
+		{"refs/heads/topic", side},
+		{merge, merge},
+		{merge[:7], merge},
+		{strings.ToUpper(merge[:8]), merge},
+		{"HEAD~1", main},
+		{"HEAD~", main},
+		{"HEAD~2", root},
+		{"HEAD^", main},
+		{"HEAD^2", side},
+		{"HEAD^2~1", root},
+		{"master^0", merge},
+		{"topic~1", root},
+		{"master@{0}", merge},
+		{"master@{1}", main},
+		{"master@{1}~1", root},
+		{"HEAD@{1}", main},
+		{"@{1}", main},
+	}
+
+	for _, test := range tests {
+		hash, err := Resolve(repo, db, test.expr)
+		if err != nil {
+			t.Errorf("Resolve(%s) failed: %v", test.expr, err)
+			continue
+		}
+		if hash != test.expected {
+			t.Errorf("Resolve(%s) = %s, expected %s", test.expr, hash, test.expected)
+		}
+	}
+}
+
+func TestResolveErrors(t *testing.T) {
+	repo, db := tempRepo(t)
+	root := storeCommit(t, db, "root")
+	setBranch(t, repo, "master", root)
+
+	for _, expr := range []string{
+		"",
+		"missing",
+		"abc",
+		"ffffffff",
+		"HEAD~1",
+		"HEAD^2",
+		"master@{5}",
+		"master@{yesterday}",
+	} {
+		if _, err := Resolve(repo, db, expr); err == nil {
+			t.Errorf("Expected Resolve(%q) to fail", expr)
+		}
+	}
+}
+
+func TestResolveAmbiguousPrefix(t *testing.T) {
+	repo, db := tempRepo(t)
+
+	// Store commits until two hashes share a four-character prefix.
+	seen := make(map[string]string)
+	var prefix string
+	for i := 0; prefix == ""; i++ {
+		hash := storeCommit(t, db, strings.Repeat("x", i))
+		if _, exists := seen[hash[:4]]; exists {
+			prefix = hash[:4]
+		}
+		seen[hash[:4]] = hash
+	}
+
+	_, err := Resolve(repo, db, prefix)
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Errorf("Expected an ambiguity error for %s, got %v", prefix, err)
+	}
+}
+
+func TestResolveCommitRejectsOtherObjects(t *testing.T) {
+	repo, db := tempRepo(t)
+
+	b, _ := db.NewBlob([]byte("content\n"))
+	hash, err := db.Put(b)
+	if err != nil {
+		t.Fatalf("Failed to store blob: %v", err)
+	}
+
+	if resolved, err := Resolve(repo, db, hash[:10]); err != nil || resolved != hash {
+		t.Errorf("Expected Resolve to find the blob, got %s (%v)", resolved, err)
+	}
+	if _, err := ResolveCommit(repo, db, hash[:10]); err == nil {
+		t.Errorf("Expected ResolveCommit to reject a blob")
+	}
+}
+
+func TestResolveTags(t *testing.T) {
+	repo, db := tempRepo(t)
+	root := storeCommit(t, db, "root")
+	child := storeCommit(t, db, "child", root)
+	setBranch(t, repo, "master", child)
+
+	annotated, err := db.Put(tag.NewTag(child, "commit", "v1.0", "John Doe <john@example.com>", "Release"))
+	if err != nil {
+		t.Fatalf("Failed to store tag: %v", err)
+	}
+	if err := refs.Write(repo, "refs/tags/v1.0", annotated); err != nil {
+		t.Fatalf("Failed to write tag ref: %v", err)
+	}
+	if err := refs.Write(repo, "refs/tags/light", root); err != nil {
+		t.Fatalf("Failed to write tag ref: %v", err)
+	}
+
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{"v1.0", annotated},
+		{"tags/v1.0", annotated},
+		{"refs/tags/v1.0", annotated},
+		{"v1.0~1", root},
+		{"v1.0^0", child},
+		{"light", root},
+	}
+	for _, test := range tests {
+		hash, err := Resolve(repo, db, test.expr)
+		if err != nil {
+			t.Errorf("Resolve(%s) failed: %v", test.expr, err)
+			continue
+		}
+		if hash != test.expected {
+			t.Errorf("Resolve(%s) = %s, expected %s", test.expr, hash, test.expected)
+		}
+	}
+
+	hash, err := ResolveCommit(repo, db, "v1.0")
+	if err != nil || hash != child {
+		t.Errorf("Expected ResolveCommit to peel v1.0 to %s, got %s (%v)", child, hash, err)
+	}
+}
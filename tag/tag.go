@@ -0,0 +1,99 @@
+package tag
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/nexxeln/mini-git/identity"
+)
+
+// Tag is an annotated tag: a named, signed-off pointer to another object,
+// usually a commit.
+type Tag struct {
+	ObjectHash string
+	ObjectType string
+	Name       string
+	Tagger     string
+	TaggerDate time.Time
+	Message    string
+}
+
+func NewTag(objectHash, objectType, name, tagger, message string) *Tag {
+	return &Tag{
+		ObjectHash: objectHash,
+		ObjectType: objectType,
+		Name:       name,
+		Tagger:     tagger,
+		TaggerDate: time.Now(),
+		Message:    message,
+	}
+}
+
+func (t *Tag) Serialize() ([]byte, error) {
+	var buffer bytes.Buffer
+
+	buffer.WriteString(fmt.Sprintf("object %s\n", t.ObjectHash))
+	buffer.WriteString(fmt.Sprintf("type %s\n", t.ObjectType))
+	buffer.WriteString(fmt.Sprintf("tag %s\n", t.Name))
+	buffer.WriteString(fmt.Sprintf("tagger %s %s\n", t.Tagger, identity.FormatTime(t.TaggerDate)))
+	buffer.WriteString("\n")
+	buffer.WriteString(t.Message)
+
+	content := buffer.Bytes()
+	header := fmt.Sprintf("tag %d\x00", len(content))
+	return append([]byte(header), content...), nil
+}
+
+func Deserialize(data []byte) (*Tag, error) {
+	nullIndex := bytes.IndexByte(data, 0)
+	if nullIndex == -1 {
+		return nil, fmt.Errorf("invalid tag data: no null byte found")
+	}
+
+	header := string(data[:nullIndex])
+	if !strings.HasPrefix(header, "tag ") {
+		return nil, fmt.Errorf("invalid tag data: incorrect header")
+	}
+
+	content := data[nullIndex+1:]
+	tag := &Tag{}
+
+	fields, message, _ := bytes.Cut(content, []byte("\n\n"))
+	for _, line := range bytes.Split(fields, []byte("\n")) {
+		key, value, found := strings.Cut(string(line), " ")
+		if !found {
+			return nil, fmt.Errorf("invalid tag line: %s", string(line))
+		}
+
+		switch key {
+		case "object":
+			tag.ObjectHash = value
+		case "type":
+			tag.ObjectType = value
+		case "tag":
+			tag.Name = value
+		case "tagger":
+			tag.Tagger, tag.TaggerDate = identity.ParseSignature(value)
+		default:
+			return nil, fmt.Errorf("unknown tag field: %s", key)
+		}
+	}
+
+	if tag.ObjectHash == "" || tag.ObjectType == "" || tag.Name == "" {
+		return nil, fmt.Errorf("invalid tag data: missing object, type or tag name")
+	}
+
+	tag.Message = strings.TrimSpace(string(message))
+
+	return tag, nil
+}
+
+func (t *Tag) Hash() string {
+	serialized, _ := t.Serialize()
+	hash := sha1.Sum(serialized)
+	return hex.EncodeToString(hash[:])
+}
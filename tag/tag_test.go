@@ -0,0 +1,81 @@
+package tag
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTagSerializeDeserialize(t *testing.T) {
+	objectHash := "0123456789abcdef0123456789abcdef01234567"
+	tagger := "John Doe <john@example.com>"
+
+	original := NewTag(objectHash, "commit", "v1.0", tagger, "Release 1.0\n\nFirst stable release.")
+	original.TaggerDate = time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC)
+
+	serialized, err := original.Serialize()
+	if err != nil {
+		t.Fatalf("Failed to serialize tag: %v", err)
+	}
+
+	expected := "tag 156\x00object 0123456789abcdef0123456789abcdef01234567\n" +
+		"type commit\n" +
+		"tag v1.0\n" +
+		"tagger John Doe <john@example.com> 1625140800 +0000\n" +
+		"\n" +
+		"Release 1.0\n\nFirst stable release."
+	if string(serialized) != expected {
+		t.Errorf("Unexpected serialization:\n%q\nexpected:\n%q", serialized, expected)
+	}
+
+	deserialized, err := Deserialize(serialized)
+	if err != nil {
+		t.Fatalf("Failed to deserialize tag: %v", err)
+	}
+
+	if deserialized.ObjectHash != objectHash || deserialized.ObjectType != "commit" || deserialized.Name != "v1.0" {
+		t.Errorf("Deserialized tag target does not match: %+v", deserialized)
+	}
+	if deserialized.Tagger != tagger || !deserialized.TaggerDate.Equal(original.TaggerDate) {
+		t.Errorf("Deserialized tagger does not match: %s %v", deserialized.Tagger, deserialized.TaggerDate)
+	}
+	if deserialized.Message != original.Message {
+		t.Errorf("Expected message %q, got %q", original.Message, deserialized.Message)
+	}
+	if deserialized.Hash() != original.Hash() {
+		t.Errorf("Round trip changed the hash: %s != %s", deserialized.Hash(), original.Hash())
+	}
+}
+
+func TestDeserializeInvalidTag(t *testing.T) {
+	for _, data := range []string{
+		"no null byte",
+		"commit 5\x00hello",
+		"tag 11\x00type commit",
+		"tag 20\x00object abc\nbogus x\n\nmsg",
+	} {
+		if _, err := Deserialize([]byte(data)); err == nil {
+			t.Errorf("Expected an error deserializing %q", data)
+		}
+	}
+}
+
+func TestTagTimezoneRoundTrip(t *testing.T) {
+	original := NewTag("0123456789abcdef0123456789abcdef01234567", "commit", "v1.0", "John Doe <john@example.com>", "Release")
+	original.TaggerDate = time.Date(2021, 7, 1, 14, 0, 0, 0, time.FixedZone("", 2*60*60))
+
+	serialized, err := original.Serialize()
+	if err != nil {
+		t.Fatalf("Failed to serialize tag: %v", err)
+	}
+
+	deserialized, err := Deserialize(serialized)
+	if err != nil {
+		t.Fatalf("Failed to deserialize tag: %v", err)
+	}
+	if _, offset := deserialized.TaggerDate.Zone(); offset != 2*60*60 || !deserialized.TaggerDate.Equal(original.TaggerDate) {
+		t.Errorf("Expected 2021-07-01 14:00 +0200, got %v", deserialized.TaggerDate)
+	}
+	if deserialized.Hash() != original.Hash() {
+		t.Errorf("Round trip changed the hash: %s != %s", deserialized.Hash(), original.Hash())
+	}
+}
@@ -0,0 +1,99 @@
+package tree
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+func (t *Tree) serializeGit() ([]byte, error) {
+	var buffer bytes.Buffer
+	for _, entry := range sortedEntries(t.Entries) {
+		if entry.Name == "" || strings.IndexByte(entry.Name, 0) != -1 {
+			return nil, fmt.Errorf("invalid tree entry name %q", entry.Name)
+		}
+
+		rawHash, err := hex.DecodeString(entry.Hash)
+		if err != nil || len(rawHash) != 20 {
+			return nil, fmt.Errorf("invalid hash for tree entry %s: %s", entry.Name, entry.Hash)
+		}
+
+		mode := "100644"
+		if entry.Type == EntryTypeTree {
+			mode = "40000"
+		}
+
+		buffer.WriteString(mode)
+		buffer.WriteByte(' ')
+		buffer.WriteString(entry.Name)
+		buffer.WriteByte(0)
+		buffer.Write(rawHash)
+	}
+
+	content := buffer.Bytes()
+	header := fmt.Sprintf("tree %d\x00", len(content))
+	return append([]byte(header), content...), nil
+}
+
+// DeserializeGit parses a tree written in Git's binary encoding.
+func DeserializeGit(data []byte) (*Tree, error) {
+	nullIndex := bytes.IndexByte(data, 0)
+	if nullIndex == -1 {
+		return nil, fmt.Errorf("invalid tree data: no null byte found")
+	}
+
+	header := string(data[:nullIndex])
+	if !strings.HasPrefix(header, "tree ") {
+		return nil, fmt.Errorf("invalid tree data: incorrect header")
+	}
+
+	tree := NewTree()
+	tree.Encoding = EncodingGit
+
+	content := data[nullIndex+1:]
+	for len(content) > 0 {
+		spaceIndex := bytes.IndexByte(content, ' ')
+		if spaceIndex == -1 {
+			return nil, fmt.Errorf("invalid tree entry: missing mode")
+		}
+		mode := string(content[:spaceIndex])
+		content = content[spaceIndex+1:]
+
+		nameEnd := bytes.IndexByte(content, 0)
+		if nameEnd == -1 || len(content) < nameEnd+21 {
+			return nil, fmt.Errorf("invalid tree entry: truncated entry")
+		}
+		name := string(content[:nameEnd])
+		hash := hex.EncodeToString(content[nameEnd+1 : nameEnd+21])
+		content = content[nameEnd+21:]
+
+		entryType := EntryTypeBlob
+		if mode == "40000" {
+			entryType = EntryTypeTree
+		}
+
+		tree.AddEntry(name, hash, entryType)
+	}
+
+	return tree, nil
+}
+
+// sortedEntries returns a copy of entries in the order Git requires in tree
+// objects: by name, with subtree names compared as if they ended in a slash.
+func sortedEntries(entries []Entry) []Entry {
+	sorted := make([]Entry, len(entries))
+	copy(sorted, entries)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return gitSortKey(sorted[i]) < gitSortKey(sorted[j])
+	})
+	return sorted
+}
+
+func gitSortKey(entry Entry) string {
+	if entry.Type == EntryTypeTree {
+		return entry.Name + "/"
+	}
+	return entry.Name
+}
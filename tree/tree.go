@@ -5,6 +5,7 @@ import (
 	"crypto/sha1"
 	"encoding/hex"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -22,8 +23,18 @@ type Entry struct {
 	Type EntryType
 }
 
+type Encoding int
+
+const (
+	// EncodingText writes one "mode type hash\tname" line per entry.
+	EncodingText Encoding = iota
+	// EncodingGit writes Git's binary "mode name\0<20-byte hash>" entries.
+	EncodingGit
+)
+
 type Tree struct {
-	Entries []Entry
+	Entries  []Entry
+	Encoding Encoding
 }
 
 func NewTree() *Tree {
@@ -32,18 +43,62 @@ func NewTree() *Tree {
 	}
 }
 
+// AddEntry inserts an entry in Git's tree order, so a tree's hash does not
+// depend on the order entries were added in. An existing entry with the same
+// name is replaced.
 func (t *Tree) AddEntry(name, hash string, entryType EntryType) {
-	t.Entries = append(t.Entries, Entry{
+	entry := Entry{
 		Name: name,
 		Hash: hash,
 		Type: entryType,
+	}
+
+	for i, existing := range t.Entries {
+		if existing.Name == name {
+			t.Entries = append(t.Entries[:i], t.Entries[i+1:]...)
+			break
+		}
+	}
+
+	key := gitSortKey(entry)
+	i := sort.Search(len(t.Entries), func(i int) bool {
+		return gitSortKey(t.Entries[i]) > key
 	})
+	t.Entries = append(t.Entries, Entry{})
+	copy(t.Entries[i+1:], t.Entries[i:])
+	t.Entries[i] = entry
+}
+
+func ParseEncoding(name string) (Encoding, error) {
+	switch name {
+	case "", "text":
+		return EncodingText, nil
+	case "binary", "git":
+		return EncodingGit, nil
+	}
+	return EncodingText, fmt.Errorf("unknown tree encoding: %s", name)
+}
+
+// DeserializeEncoding parses a tree written in the given encoding.
+func DeserializeEncoding(data []byte, encoding Encoding) (*Tree, error) {
+	if encoding == EncodingGit {
+		return DeserializeGit(data)
+	}
+	return Deserialize(data)
 }
 
 func (t *Tree) Serialize() ([]byte, error) {
+	if t.Encoding == EncodingGit {
+		return t.serializeGit()
+	}
+
 	var buffer bytes.Buffer
 
-	for _, entry := range t.Entries {
+	for _, entry := range sortedEntries(t.Entries) {
+		if entry.Name == "" || strings.ContainsAny(entry.Name, "\t\n") {
+			return nil, fmt.Errorf("tree entry name %q cannot be stored in the text encoding; use the binary encoding", entry.Name)
+		}
+
 		entryString := fmt.Sprintf("%06o %s %s\t%s\n",
 			func() int {
 				if entry.Type == EntryTypeBlob {
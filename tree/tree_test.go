@@ -102,3 +102,171 @@ func TestTreeDeserialize(t *testing.T) {
 		}
 	}
 }
+
+func TestTreeDeserializeSubtree(t *testing.T) {
+	subtree := NewTree()
+	b, _ := blob.NewBlob([]byte("nested content"))
+	subtree.AddEntry("b.go", b.Hash, EntryTypeBlob)
+
+	root := NewTree()
+	root.AddEntry("src", subtree.Hash(), EntryTypeTree)
+	root.AddEntry("top.txt", b.Hash, EntryTypeBlob)
+
+	serialized, _ := root.Serialize()
+	deserialized, err := Deserialize(serialized)
+	if err != nil {
+		t.Fatalf("Failed to deserialize tree: %v", err)
+	}
+
+	if len(deserialized.Entries) != 2 {
+		t.Fatalf("Deserialized tree should have 2 entries, got %d", len(deserialized.Entries))
+	}
+
+	if deserialized.Entries[0].Type != EntryTypeTree || deserialized.Entries[0].Hash != subtree.Hash() {
+		t.Errorf("Expected subtree entry %s, got %+v", subtree.Hash(), deserialized.Entries[0])
+	}
+
+	if deserialized.Entries[1].Type != EntryTypeBlob {
+		t.Errorf("Expected blob entry, got %+v", deserialized.Entries[1])
+	}
+
+	if deserialized.Hash() != root.Hash() {
+		t.Errorf("Deserialized tree hash should be %s, got %s", root.Hash(), deserialized.Hash())
+	}
+}
+
+func TestGitEncodingMatchesGit(t *testing.T) {
+	helloHash := "3b18e512dba79e4c8300dd08aeb37f8e728b8dad"
+
+	subtree := NewTree()
+	subtree.Encoding = EncodingGit
+	subtree.AddEntry("hello.txt", helloHash, EntryTypeBlob)
+
+	// Hashes as reported by git mktree
+	if subtree.Hash() != "68aba62e560c0ebc3396e8ae9335232cd93a3f60" {
+		t.Errorf("Unexpected subtree hash %s", subtree.Hash())
+	}
+
+	root := NewTree()
+	root.Encoding = EncodingGit
+	root.AddEntry("a.txt", helloHash, EntryTypeBlob)
+	root.AddEntry("a", subtree.Hash(), EntryTypeTree)
+	root.AddEntry("a-b", helloHash, EntryTypeBlob)
+
+	if root.Hash() != "ac58f938879862b7458ec3978ed3cd817e816d6c" {
+		t.Errorf("Unexpected root tree hash %s", root.Hash())
+	}
+}
+
+func TestGitEncodingRoundTrip(t *testing.T) {
+	original := NewTree()
+	original.Encoding = EncodingGit
+	b, _ := blob.NewGitBlob([]byte("content"))
+	original.AddEntry("file.txt", b.Hash, EntryTypeBlob)
+	original.AddEntry("dir", b.Hash, EntryTypeTree)
+
+	serialized, err := original.Serialize()
+	if err != nil {
+		t.Fatalf("Failed to serialize tree: %v", err)
+	}
+
+	deserialized, err := DeserializeGit(serialized)
+	if err != nil {
+		t.Fatalf("Failed to deserialize tree: %v", err)
+	}
+
+	if len(deserialized.Entries) != 2 {
+		t.Fatalf("Deserialized tree should have 2 entries, got %d", len(deserialized.Entries))
+	}
+	if deserialized.Entries[0].Name != "dir" || deserialized.Entries[0].Type != EntryTypeTree {
+		t.Errorf("Expected subtree entry 'dir' first, got %+v", deserialized.Entries[0])
+	}
+	if deserialized.Entries[1].Name != "file.txt" || deserialized.Entries[1].Hash != b.Hash {
+		t.Errorf("Expected blob entry 'file.txt', got %+v", deserialized.Entries[1])
+	}
+	if deserialized.Hash() != original.Hash() {
+		t.Errorf("Deserialized tree hash should be %s, got %s", original.Hash(), deserialized.Hash())
+	}
+}
+
+func TestTreeHashIndependentOfInsertionOrder(t *testing.T) {
+	b1, _ := blob.NewBlob([]byte("one"))
+	b2, _ := blob.NewBlob([]byte("two"))
+
+	for _, encoding := range []Encoding{EncodingText, EncodingGit} {
+		first := NewTree()
+		first.Encoding = encoding
+		first.AddEntry("b.txt", b1.Hash, EntryTypeBlob)
+		first.AddEntry("a", b2.Hash, EntryTypeTree)
+		first.AddEntry("a.txt", b2.Hash, EntryTypeBlob)
+
+		second := NewTree()
+		second.Encoding = encoding
+		second.AddEntry("a.txt", b2.Hash, EntryTypeBlob)
+		second.AddEntry("a", b2.Hash, EntryTypeTree)
+		second.AddEntry("b.txt", b1.Hash, EntryTypeBlob)
+
+		if first.Hash() != second.Hash() {
+			t.Errorf("Encoding %d: tree hash depends on insertion order", encoding)
+		}
+
+		// Git compares subtree names as if they ended in a slash
+		expected := []string{"a.txt", "a", "b.txt"}
+		for i, name := range expected {
+			if first.Entries[i].Name != name {
+				t.Errorf("Encoding %d: entry %d should be %q, got %q", encoding, i, name, first.Entries[i].Name)
+			}
+		}
+	}
+}
+
+func TestAddEntryReplacesExistingName(t *testing.T) {
+	tree := NewTree()
+	b1, _ := blob.NewBlob([]byte("one"))
+	b2, _ := blob.NewBlob([]byte("two"))
+	tree.AddEntry("file.txt", b1.Hash, EntryTypeBlob)
+	tree.AddEntry("file.txt", b2.Hash, EntryTypeBlob)
+
+	if len(tree.Entries) != 1 {
+		t.Fatalf("Tree should have 1 entry, got %d", len(tree.Entries))
+	}
+	if tree.Entries[0].Hash != b2.Hash {
+		t.Errorf("Entry hash should be '%s', got '%s'", b2.Hash, tree.Entries[0].Hash)
+	}
+}
+
+func TestNamesWithTabsAndNewlines(t *testing.T) {
+	b, _ := blob.NewBlob([]byte("content"))
+	names := []string{"tab\there.txt", "new\nline.txt"}
+
+	textTree := NewTree()
+	for _, name := range names {
+		textTree.AddEntry(name, b.Hash, EntryTypeBlob)
+	}
+	if _, err := textTree.Serialize(); err == nil {
+		t.Errorf("Expected the text encoding to reject names with tabs or newlines")
+	}
+
+	binaryTree := NewTree()
+	binaryTree.Encoding = EncodingGit
+	for _, name := range names {
+		binaryTree.AddEntry(name, b.Hash, EntryTypeBlob)
+	}
+	serialized, err := binaryTree.Serialize()
+	if err != nil {
+		t.Fatalf("Failed to serialize tree: %v", err)
+	}
+
+	deserialized, err := DeserializeEncoding(serialized, EncodingGit)
+	if err != nil {
+		t.Fatalf("Failed to deserialize tree: %v", err)
+	}
+	if len(deserialized.Entries) != len(binaryTree.Entries) {
+		t.Fatalf("Deserialized tree should have %d entries, got %d", len(binaryTree.Entries), len(deserialized.Entries))
+	}
+	for i, entry := range binaryTree.Entries {
+		if deserialized.Entries[i].Name != entry.Name {
+			t.Errorf("Entry %d: name should be %q, got %q", i, entry.Name, deserialized.Entries[i].Name)
+		}
+	}
+}